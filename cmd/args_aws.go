@@ -42,6 +42,19 @@ func init() {
 		}
 		viper.SetDefault(key, awsservice.DefaultConfDir)
 	}
+	{
+		const (
+			key         = awsservice.KeyWatchConfDir
+			longOpt     = "aws-watch-conf-dir"
+			description = "Watch AWS configuration directory, apply changes automatically"
+		)
+
+		RootCmd.PersistentFlags().Bool(longOpt, awsservice.DefaultWatchConfDir, description)
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		viper.SetDefault(key, awsservice.DefaultWatchConfDir)
+	}
 	{
 		const (
 			key         = awsservice.KeyConfExample
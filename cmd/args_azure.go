@@ -42,6 +42,19 @@ func init() {
 		}
 		viper.SetDefault(key, azureservice.DefaultConfDir)
 	}
+	{
+		const (
+			key         = azureservice.KeyWatchConfDir
+			longOpt     = "azure-watch-conf-dir"
+			description = "Watch Azure configuration directory, apply changes automatically"
+		)
+
+		RootCmd.PersistentFlags().Bool(longOpt, azureservice.DefaultWatchConfDir, description)
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		viper.SetDefault(key, azureservice.DefaultWatchConfDir)
+	}
 	{
 		const (
 			key         = azureservice.KeyConfExample
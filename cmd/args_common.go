@@ -39,6 +39,24 @@ func init() {
 		viper.SetDefault(key, defaults.Debug)
 	}
 
+	{
+		const (
+			key         = config.KeyListen
+			longOpt     = "listen"
+			envVar      = release.ENVPREFIX + "_LISTEN"
+			description = "Listen address for status server (/health, /status, /debug/vars) e.g. ':8080' [disabled if empty]"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.Listen, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.Listen)
+	}
+
 	{
 		const (
 			key         = config.KeyLogLevel
@@ -75,17 +93,255 @@ func init() {
 		viper.SetDefault(key, defaults.LogPretty)
 	}
 
-	// {
-	// 	const (
-	// 		key         = config.KeyPipeSubmits
-	// 		longOpt     = "pipe-submits"
-	// 		envVar      = release.ENVPREFIX + "_PIPE_SUBMITS"
-	// 		description = "Pipe metric submissions to Circonus (experimental)"
-	// 	)
+	{
+		const (
+			key         = config.KeySubmitTimeout
+			longOpt     = "submit-timeout"
+			envVar      = release.ENVPREFIX + "_SUBMIT_TIMEOUT"
+			description = "Timeout for metric submission requests to the broker (e.g. 60s) [0 = no timeout]"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.SubmitTimeout, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitTimeout)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitIdleTimeout
+			longOpt     = "submit-idle-timeout"
+			envVar      = release.ENVPREFIX + "_SUBMIT_IDLE_TIMEOUT"
+			description = "How long idle broker connections are kept for reuse (e.g. 90s) [0 = no limit]"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.SubmitIdleTimeout, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitIdleTimeout)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitMaxConns
+			longOpt     = "submit-max-conns"
+			envVar      = release.ENVPREFIX + "_SUBMIT_MAX_CONNS"
+			description = "Maximum connections to the broker, per check [0 = no limit]"
+		)
+
+		RootCmd.PersistentFlags().Int(longOpt, defaults.SubmitMaxConns, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitMaxConns)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitMaxIdleConns
+			longOpt     = "submit-max-idle-conns"
+			envVar      = release.ENVPREFIX + "_SUBMIT_MAX_IDLE_CONNS"
+			description = "Maximum idle connections kept to the broker for reuse, per check"
+		)
+
+		RootCmd.PersistentFlags().Int(longOpt, defaults.SubmitMaxIdleConns, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitMaxIdleConns)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitRetries
+			longOpt     = "submit-retries"
+			envVar      = release.ENVPREFIX + "_SUBMIT_RETRIES"
+			description = "Number of times to retry a failed metric submission (with exponential backoff)"
+		)
+
+		RootCmd.PersistentFlags().Int(longOpt, defaults.SubmitRetries, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitRetries)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitSpoolDir
+			longOpt     = "submit-spool-dir"
+			envVar      = release.ENVPREFIX + "_SUBMIT_SPOOL_DIR"
+			description = "Directory to spool metric submissions which failed after retries, replayed when the broker is reachable [disabled if empty]"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.SubmitSpoolDir, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitSpoolDir)
+	}
+
+	{
+		const (
+			key         = config.KeySubmitSpoolMaxMB
+			longOpt     = "submit-spool-max-mb"
+			envVar      = release.ENVPREFIX + "_SUBMIT_SPOOL_MAX_MB"
+			description = "Maximum size of the submission spool, per check, in MB (oldest payloads are dropped)"
+		)
+
+		RootCmd.PersistentFlags().Int(longOpt, defaults.SubmitSpoolMaxMB, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.SubmitSpoolMaxMB)
+	}
+
+	{
+		const (
+			key         = config.KeyStateFile
+			longOpt     = "state-file"
+			envVar      = release.ENVPREFIX + "_STATE_FILE"
+			description = "File the end of the last successful collection, per instance collector, is persisted to, so collections resume after a restart [disabled if empty]"
+		)
 
-	// 	RootCmd.PersistentFlags().Bool(longOpt, false, envDescription(description, envVar))
-	// 	viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt))
-	// 	viper.BindEnv(key, envVar)
-	// 	viper.SetDefault(key, false)
-	// }
+		RootCmd.PersistentFlags().String(longOpt, defaults.StateFile, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.StateFile)
+	}
+
+	{
+		const (
+			key         = config.KeyStateMaxLookback
+			longOpt     = "state-max-lookback"
+			envVar      = release.ENVPREFIX + "_STATE_MAX_LOOKBACK"
+			description = "Maximum time range collected after a restart, when resuming from the state file"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.StateMaxLookback, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.StateMaxLookback)
+	}
+
+	{
+		const (
+			key         = config.KeyDedup
+			longOpt     = "dedup"
+			envVar      = release.ENVPREFIX + "_DEDUP"
+			description = "Skip samples already delivered for a series, collections request overlapping time ranges"
+		)
+
+		RootCmd.PersistentFlags().Bool(longOpt, defaults.Dedup, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.Dedup)
+	}
+
+	{
+		const (
+			key         = config.KeyDedupRevisionWindow
+			longOpt     = "dedup-revision-window"
+			envVar      = release.ENVPREFIX + "_DEDUP_REVISION_WINDOW"
+			description = "With --dedup, samples within this window of the last delivered for a series are delivered again (revised by late arriving data)"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.DedupRevisionWindow, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.DedupRevisionWindow)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckReconcile
+			longOpt     = "check-reconcile"
+			envVar      = release.ENVPREFIX + "_CHECK_RECONCILE"
+			description = "Reconcile existing check bundles (display name, tags, notes, metric filters) with the configuration (update|log|off)"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.CheckReconcile, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.CheckReconcile)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckMultipleActive
+			longOpt     = "check-multiple-active"
+			envVar      = release.ENVPREFIX + "_CHECK_MULTIPLE_ACTIVE"
+			description = "Resolve multiple active check bundles found for an instance (error|oldest|notes|deactivate)"
+		)
+
+		RootCmd.PersistentFlags().String(longOpt, defaults.CheckMultipleActive, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.CheckMultipleActive)
+	}
+
+	{
+		const (
+			key         = config.KeyPipeSubmits
+			longOpt     = "pipe-submits"
+			envVar      = release.ENVPREFIX + "_PIPE_SUBMITS"
+			description = "Stream metric submissions to Circonus while collecting, rather than buffering each submission in memory"
+		)
+
+		RootCmd.PersistentFlags().Bool(longOpt, defaults.PipeSubmits, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaults.PipeSubmits)
+	}
 }
@@ -42,6 +42,19 @@ func init() {
 		}
 		viper.SetDefault(key, gcpservice.DefaultConfDir)
 	}
+	{
+		const (
+			key         = gcpservice.KeyWatchConfDir
+			longOpt     = "gcp-watch-conf-dir"
+			description = "Watch GCP configuration directory, apply changes automatically"
+		)
+
+		RootCmd.PersistentFlags().Bool(longOpt, gcpservice.DefaultWatchConfDir, description)
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		viper.SetDefault(key, gcpservice.DefaultWatchConfDir)
+	}
 	{
 		const (
 			key         = gcpservice.KeyConfExample
@@ -0,0 +1,133 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/azureservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	backfillStart, backfillEnd string
+	backfillChunk              time.Duration
+	backfillMaxAPIRate         float64
+)
+
+// backfillCmd collects a past time range for one service instance, to fill gaps after an outage.
+var backfillCmd = &cobra.Command{
+	Use:   "backfill <aws|azure|gcp> <instance id>",
+	Short: "Collect and submit metrics for a past time range for one service instance",
+	Long: `Finds the configuration with the instance id in the service configuration
+directory (e.g. --aws-conf-dir) and collects the time range from --start to
+--end in consecutive --chunk sized collections, oldest first, submitting the
+timestamped samples (or, with --dry-run, writing them without any Circonus
+API calls). Used to fill gaps, e.g. after an outage, while the cloud service
+still retains the data. Between chunks it pauses, if necessary, to keep the
+cloud api calls at or below --max-api-rate per second. Writes a summary of
+each collector to stderr, the exit code is non-zero if any chunk failed. The
+state file (see --state-file) is neither read nor updated.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if backfillStart == "" {
+			log.Fatal().Msg("--start is required")
+		}
+		now := time.Now()
+		start, err := parseTimeFlag(backfillStart, now)
+		if err != nil {
+			log.Fatal().Err(err).Msg("--start")
+		}
+		end, err := parseTimeFlag(backfillEnd, now)
+		if err != nil {
+			log.Fatal().Err(err).Msg("--end")
+		}
+		if end.IsZero() {
+			end = now
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		svcID, id := args[0], args[1]
+		confDir, err := serviceConfDir(svcID)
+		if err != nil {
+			log.Fatal().Err(err).Str("service", svcID).Msg("backfill")
+		}
+		cfgFile, err := services.FindConfigFile(confDir, id)
+		if err != nil {
+			log.Fatal().Err(err).Str("service", svcID).Msg("finding instance config")
+		}
+		svc, err := newServiceFromFile(ctx, svcID, cfgFile, false)
+		if err != nil {
+			log.Fatal().Err(err).Str("service", svcID).Str("config_file", cfgFile).Msg("loading config")
+		}
+
+		log.Info().
+			Str("service", svcID).
+			Str("id", id).
+			Str("config_file", cfgFile).
+			Time("start", start).
+			Time("end", end).
+			Str("chunk", backfillChunk.String()).
+			Bool("dry_run", viper.GetBool(config.KeyDryRun)).Msg("backfilling")
+
+		opts := services.BackfillOptions{Chunk: backfillChunk, MaxAPIRate: backfillMaxAPIRate}
+		backfillResults, err := services.Backfill(ctx, svc, services.TimeRange{Start: start, End: end}, opts, log.With().Str("service", svcID).Str("id", id).Logger())
+		if cerr := circonus.CloseDryRunOutputs(); cerr != nil {
+			log.Error().Err(cerr).Msg("closing dry run output")
+		}
+		results := map[string][]services.CollectionResult{svcID: backfillResults}
+		if werr := services.WriteResults(os.Stderr, results); werr != nil {
+			log.Error().Err(werr).Msg("writing collection results")
+		}
+		if err != nil {
+			stop()
+			log.Fatal().Err(err).Msg("backfill")
+		}
+		for _, r := range results[svcID] {
+			if r.Failed() {
+				stop()
+				log.Fatal().Str("id", r.ID).Msg("backfill failed")
+			}
+		}
+	},
+}
+
+// serviceConfDir returns the configuration directory for the service (aws, azure, gcp).
+func serviceConfDir(svcID string) (string, error) {
+	switch svcID {
+	case "aws":
+		return viper.GetString(awsservice.KeyConfDir), nil
+	case "azure":
+		return viper.GetString(azureservice.KeyConfDir), nil
+	case "gcp":
+		return viper.GetString(gcpservice.KeyConfDir), nil
+	default:
+		return "", errors.Errorf("unknown service (%s), expected aws, azure, or gcp", svcID)
+	}
+}
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillStart, "start", "", "REQUIRED start of the backfill time range, RFC3339 timestamp or duration before now (e.g. 6h)")
+	backfillCmd.Flags().StringVar(&backfillEnd, "end", "", "End of the backfill time range, RFC3339 timestamp or duration before now (e.g. 1h) [default: now]")
+	backfillCmd.Flags().DurationVar(&backfillChunk, "chunk", time.Hour, "Size of the time range collected at once, a multiple of 1m")
+	backfillCmd.Flags().Float64Var(&backfillMaxAPIRate, "max-api-rate", 5, "Average cloud api calls per second not to exceed, 0 for no limit")
+
+	RootCmd.AddCommand(backfillCmd)
+}
@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/azureservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	collectOnceStart, collectOnceEnd string
+	collectOnceResume                bool
+)
+
+// collectOnceCmd runs a single collection for one service configuration file.
+var collectOnceCmd = &cobra.Command{
+	Use:   "collect-once <aws|azure|gcp> <config file>",
+	Short: "Run a single collection for one service configuration file and exit",
+	Long: `Loads a single service configuration file, runs exactly one collection
+for each instance it defines (e.g. each AWS region), submits the metrics (or,
+with --dry-run, writes them without any Circonus API calls), writes a summary
+of each collector to stderr and exits. The exit code is non-zero if any
+collector failed. The state file (see --state-file) is neither read nor updated
+unless --resume is given.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		now := time.Now()
+		start, err := parseTimeFlag(collectOnceStart, now)
+		if err != nil {
+			log.Fatal().Err(err).Msg("--start")
+		}
+		end, err := parseTimeFlag(collectOnceEnd, now)
+		if err != nil {
+			log.Fatal().Err(err).Msg("--end")
+		}
+		if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+			log.Fatal().Time("start", start).Time("end", end).Msg("--start must be before --end")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		svcID, cfgFile := args[0], args[1]
+		svc, err := newServiceFromFile(ctx, svcID, cfgFile, collectOnceResume)
+		if err != nil {
+			log.Fatal().Err(err).Str("service", svcID).Str("config_file", cfgFile).Msg("loading config")
+		}
+
+		log.Info().
+			Str("service", svcID).
+			Str("config_file", cfgFile).
+			Bool("dry_run", viper.GetBool(config.KeyDryRun)).Msg("collecting once")
+
+		results := map[string][]services.CollectionResult{svcID: svc.CollectOnce(services.TimeRange{Start: start, End: end})}
+		if err := circonus.CloseDryRunOutputs(); err != nil {
+			log.Error().Err(err).Msg("closing dry run output")
+		}
+		if err := services.WriteResults(os.Stderr, results); err != nil {
+			log.Error().Err(err).Msg("writing collection results")
+		}
+		for _, r := range results[svcID] {
+			if r.Failed() {
+				stop()
+				log.Fatal().Str("id", r.ID).Msg("collection failed")
+			}
+		}
+	},
+}
+
+// newServiceFromFile returns the service (aws, azure, gcp) with the instance(s)
+// defined by cfgFile. The state file is only used if resume is true.
+func newServiceFromFile(ctx context.Context, svcID, cfgFile string, resume bool) (services.Service, error) {
+	switch svcID {
+	case "aws":
+		return awsservice.NewFromFile(ctx, cfgFile, resume)
+	case "azure":
+		return azureservice.NewFromFile(ctx, cfgFile, resume)
+	case "gcp":
+		return gcpservice.NewFromFile(ctx, cfgFile, resume)
+	default:
+		return nil, errors.Errorf("unknown service (%s), expected aws, azure, or gcp", svcID)
+	}
+}
+
+// parseTimeFlag parses a time range flag, either an RFC3339 timestamp
+// (e.g. 2019-06-01T12:00:00Z) or a duration before now (e.g. 90m). An
+// empty value returns the zero time (the service default).
+func parseTimeFlag(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time (%s), expected RFC3339 timestamp or duration before now", v)
+	}
+	if d < 0 {
+		d = -d
+	}
+	return now.Add(-d), nil
+}
+
+func init() {
+	collectOnceCmd.Flags().StringVar(&collectOnceStart, "start", "", "Start of the collection time range, RFC3339 timestamp or duration before now (e.g. 2h) [default: service default lookback]")
+	collectOnceCmd.Flags().StringVar(&collectOnceEnd, "end", "", "End of the collection time range, RFC3339 timestamp or duration before now (e.g. 1h) [default: now]")
+
+	collectOnceCmd.Flags().BoolVar(&collectOnceResume, "resume", false, "Resume from, and update, the collection watermarks in the state file, as the agent would")
+
+	RootCmd.AddCommand(collectOnceCmd)
+}
@@ -0,0 +1,37 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeFlag(t *testing.T) {
+	t.Log("Testing parseTimeFlag")
+
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]time.Time{
+		"":                     {},
+		"2019-06-01T10:30:00Z": time.Date(2019, 6, 1, 10, 30, 0, 0, time.UTC),
+		"90m":                  now.Add(-90 * time.Minute),
+		"-2h":                  now.Add(-2 * time.Hour),
+	}
+	for in, expect := range tests {
+		got, err := parseTimeFlag(in, now)
+		if err != nil {
+			t.Fatalf("expected no error for (%s), got (%s)", in, err)
+		}
+		if !got.Equal(expect) {
+			t.Fatalf("expected %s for (%s), got %s", expect, in, got)
+		}
+	}
+
+	if _, err := parseTimeFlag("yesterday", now); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -51,6 +51,24 @@ and forwards them to Circonus.`,
 			return
 		}
 
+		//
+		// dry run, collect once and exit
+		//
+		if viper.GetBool(config.KeyDryRun) {
+			log.Info().
+				Str("name", release.NAME).
+				Str("ver", release.VERSION).Msg("dry run, collecting once")
+
+			a, err := agent.New()
+			if err != nil {
+				log.Fatal().Err(err).Msg("initializing")
+			}
+			if err := a.CollectOnce(os.Stderr); err != nil {
+				log.Fatal().Err(err).Msg("dry run")
+			}
+			return
+		}
+
 		log.Info().
 			Int("pid", os.Getpid()).
 			Str("name", release.NAME).
@@ -138,6 +156,30 @@ func init() {
 		}
 	}
 
+	{
+		const (
+			key          = config.KeyDryRun
+			longOpt      = "dry-run"
+			defaultValue = false
+			description  = "Run each collection once, without Circonus API calls, write the submissions and a summary, then exit"
+		)
+		RootCmd.PersistentFlags().Bool(longOpt, defaultValue, description)
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+	}
+	{
+		const (
+			key         = config.KeyDryRunOutput
+			longOpt     = "dry-run-output"
+			description = "File to write dry run submissions to (default: stdout)"
+		)
+		RootCmd.PersistentFlags().String(longOpt, "", description)
+		if err := viper.BindPFlag(key, RootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+	}
+
 	//
 	// NOTE: all other arguments are in args_* files for organization
 	//
@@ -0,0 +1,97 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/azureservice"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// validateCmd checks the main configuration and every service configuration file.
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration files and exit",
+	Long: `Loads the main configuration file and every file in the aws, azure, and gcp
+configuration directories, checks them for unknown keys, values of the wrong
+type, missing required settings, and invalid values (e.g. stats, metric
+types), and writes each problem found with its file and field. No cloud
+service or Circonus API calls are made. The exit code is non-zero if any
+problem was found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if n := validateConfigs(os.Stdout); n > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// serviceValidators are the service instance configuration file checks, in
+// the order the services are validated.
+var serviceValidators = []struct {
+	id       string
+	validate func(cfgFile string) []error
+}{
+	{"aws", awsservice.ValidateConfigFile},
+	{"azure", azureservice.ValidateConfigFile},
+	{"gcp", gcpservice.ValidateConfigFile},
+}
+
+// validateConfigs writes the problems found with the main configuration and
+// the service configuration files to w, followed by a summary, and returns
+// the number of problems found.
+func validateConfigs(w io.Writer) int {
+	problems := 0
+	report := func(file string, errs []error) {
+		for _, err := range errs {
+			fmt.Fprintf(w, "%s: %s\n", file, err)
+			problems++
+		}
+	}
+
+	files := 0
+	if f := viper.ConfigFileUsed(); f != "" {
+		if _, err := os.Stat(f); err == nil {
+			report(f, config.ValidateConfigFile(f))
+			files++
+		}
+	}
+	report("options", config.ValidateOptions())
+
+	for _, sv := range serviceValidators {
+		confDir, _ := serviceConfDir(sv.id)
+		if confDir == "" {
+			continue
+		}
+		if _, err := os.Stat(confDir); os.IsNotExist(err) {
+			continue
+		}
+		cfgFiles, err := services.ConfigFiles(confDir)
+		if err != nil {
+			report(confDir, []error{err})
+			continue
+		}
+		for _, cf := range cfgFiles {
+			report(cf.Path, sv.validate(cf.Path))
+			files++
+		}
+	}
+
+	fmt.Fprintf(w, "%d file(s) checked, %d problem(s) found\n", files, problems)
+	return problems
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+}
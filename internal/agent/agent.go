@@ -7,11 +7,15 @@ package agent
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/signal"
+	"sync"
 
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/server"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/azureservice"
@@ -27,6 +31,7 @@ type Agent struct {
 	groupCtx    context.Context
 	groupCancel context.CancelFunc
 	services    map[string]services.Service
+	server      *server.Server
 	signalCh    chan os.Signal
 }
 
@@ -83,6 +88,11 @@ func New() (*Agent, error) {
 		log.Fatal().Msg("no cloud services enabled, must enable at least ONE")
 	}
 
+	a.server, err = server.New(a.groupCtx, a.services)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating status server")
+	}
+
 	a.signalNotifySetup()
 
 	return &a, nil
@@ -91,6 +101,9 @@ func New() (*Agent, error) {
 // Start the agent.
 func (a *Agent) Start() error {
 	a.group.Go(a.handleSignals)
+	if a.server != nil {
+		a.group.Go(a.server.Start)
+	}
 	for svcID := range a.services {
 		a.group.Go(a.services[svcID].Start)
 	}
@@ -103,6 +116,51 @@ func (a *Agent) Start() error {
 	return a.group.Wait()
 }
 
+// CollectOnce runs a single collection for every instance of each enabled
+// service, rather than starting them (e.g. dry run mode). A summary of the
+// results is written to w. Returns an error if any collection failed.
+func (a *Agent) CollectOnce(w io.Writer) error {
+	go func() { _ = a.handleSignals() }()
+	defer a.Stop()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string][]services.CollectionResult)
+	for svcID, svc := range a.services {
+		wg.Add(1)
+		go func(svcID string, svc services.Service) {
+			defer wg.Done()
+			r := svc.CollectOnce(services.TimeRange{})
+			mu.Lock()
+			results[svcID] = r
+			mu.Unlock()
+		}(svcID, svc)
+	}
+	wg.Wait()
+
+	if err := circonus.CloseDryRunOutputs(); err != nil {
+		return errors.Wrap(err, "dry run output")
+	}
+
+	if err := services.WriteResults(w, results); err != nil {
+		return errors.Wrap(err, "writing collection results")
+	}
+
+	failed := 0
+	for _, svcResults := range results {
+		for _, r := range svcResults {
+			if r.Failed() {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d collection(s) failed", failed)
+	}
+
+	return nil
+}
+
 // Stop cleans up and shuts down the Agent.
 func (a *Agent) Stop() {
 	a.stopSignalHandler()
@@ -114,6 +172,18 @@ func (a *Agent) Stop() {
 		Str("ver", release.VERSION).Msg("stopped")
 }
 
+// scanServices has each service rescan its configuration directory, starting,
+// stopping, or restarting instances for added, removed, or changed configurations.
+// Each service serializes its scans, so overlapping calls (e.g. repeated SIGHUPs)
+// are applied in turn.
+func (a *Agent) scanServices() {
+	for svcID, svc := range a.services {
+		if err := svc.Scan(); err != nil {
+			log.Error().Err(err).Str("service", svcID).Msg("scanning service configurations")
+		}
+	}
+}
+
 // stopSignalHandler disables the signal handler.
 func (a *Agent) stopSignalHandler() {
 	signal.Stop(a.signalCh)
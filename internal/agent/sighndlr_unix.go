@@ -40,7 +40,10 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, unix.SIGTERM:
 				a.Stop()
-			case unix.SIGPIPE, unix.SIGHUP:
+			case unix.SIGHUP:
+				// in the background, so the handler stays responsive (e.g. SIGTERM) during the scan
+				go a.scanServices()
+			case unix.SIGPIPE:
 				// Noop
 			case unix.SIGINFO:
 				stacklen := runtime.Stack(buf, true)
@@ -39,7 +39,10 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, syscall.SIGTERM:
 				a.Stop()
-			case syscall.SIGPIPE, syscall.SIGHUP:
+			case syscall.SIGHUP:
+				// in the background, so the handler stays responsive (e.g. SIGTERM) during the scan
+				go a.scanServices()
+			case syscall.SIGPIPE:
 				// Noop
 			case syscall.SIGTRAP:
 				stacklen := runtime.Stack(buf, true)
@@ -13,12 +13,163 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	apiclient "github.com/circonus-labs/go-apiclient"
 	"github.com/pkg/errors"
 )
 
+const (
+	// maxBrokerResponseTime is how long a broker has to accept a connection
+	// during broker selection, slower brokers are not selected.
+	maxBrokerResponseTime = 500 * time.Millisecond
+	// defaultBrokerPort is used when a broker's details do not include a port.
+	defaultBrokerPort = 43191
+)
+
+// selectBroker returns the cid of the broker to use for a new check bundle.
+// The configured broker cid is used if set, otherwise the active brokers which
+// support the check type and have all of the configured broker tags are
+// contacted and the one with the lowest latency is selected. Without broker
+// tags, if no broker can be selected, the public trap broker is used.
+func (c *Check) selectBroker() (string, error) {
+	if c.config.BrokerCID != "" {
+		return c.config.BrokerCID, nil
+	}
+
+	brokers, err := c.apih.FetchBrokers()
+	if err != nil {
+		return "", errors.Wrap(err, "fetching brokers")
+	}
+
+	module := strings.SplitN(c.checkType, ":", 2)[0]
+	broker, latency, err := pickBroker(*brokers, module, c.config.BrokerTags, maxBrokerResponseTime)
+	if err != nil {
+		if len(c.config.BrokerTags) > 0 {
+			return "", err
+		}
+		c.logger.Warn().Err(err).Str("broker", publicHTTPTrapBrokerCID).Msg("unable to select broker, using public broker")
+		return publicHTTPTrapBrokerCID, nil
+	}
+
+	c.logger.Info().Str("broker", broker.CID).Str("name", broker.Name).Str("latency", latency.String()).Msg("selected broker")
+	return broker.CID, nil
+}
+
+// pickBroker returns the broker, with all of tags, which has an active
+// instance supporting module that accepts a connection fastest (within
+// timeout) and its latency. The brokers are contacted concurrently.
+func pickBroker(brokers []apiclient.Broker, module string, tags []string, timeout time.Duration) (*apiclient.Broker, time.Duration, error) {
+	latencies := make([]time.Duration, len(brokers))
+	var wg sync.WaitGroup
+	for i := range brokers {
+		latencies[i] = -1
+		if !brokerHasTags(brokers[i], tags) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			latencies[i] = brokerLatency(brokers[i], module, timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, latency := range latencies {
+		if latency < 0 {
+			continue
+		}
+		if best == -1 || latency < latencies[best] {
+			best = i
+		}
+	}
+	if best == -1 {
+		if len(tags) > 0 {
+			return nil, 0, errors.Errorf("no reachable %s broker found with tags (%s)", module, strings.Join(tags, ","))
+		}
+		return nil, 0, errors.Errorf("no reachable %s broker found", module)
+	}
+
+	return &brokers[best], latencies[best], nil
+}
+
+// brokerHasTags returns true if the broker has every one of tags.
+func brokerHasTags(broker apiclient.Broker, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, bt := range broker.Tags {
+			if strings.EqualFold(bt, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// brokerLatency returns the shortest time taken for an active instance of the
+// broker which supports module to accept a connection, or -1 if none did
+// within timeout.
+func brokerLatency(broker apiclient.Broker, module string, timeout time.Duration) time.Duration {
+	best := time.Duration(-1)
+	for _, detail := range broker.Details {
+		if detail.Status != "active" || !brokerHasModule(detail, module) {
+			continue
+		}
+		addr := brokerAddress(detail)
+		if addr == "" {
+			continue
+		}
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			continue
+		}
+		latency := time.Since(start)
+		conn.Close()
+		if best < 0 || latency < best {
+			best = latency
+		}
+	}
+	return best
+}
+
+// brokerHasModule returns true if the broker instance supports module (e.g. httptrap).
+func brokerHasModule(detail apiclient.BrokerDetail, module string) bool {
+	for _, m := range detail.Modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// brokerAddress returns the host:port of a broker instance, the external
+// host (e.g. behind a load balancer) if set, otherwise the ip address.
+func brokerAddress(detail apiclient.BrokerDetail) string {
+	port := uint16(defaultBrokerPort)
+	if detail.ExternalHost != nil && *detail.ExternalHost != "" {
+		if detail.ExternalPort != 0 {
+			port = detail.ExternalPort
+		}
+		return net.JoinHostPort(*detail.ExternalHost, strconv.Itoa(int(port)))
+	}
+	if detail.IP == nil || *detail.IP == "" {
+		return ""
+	}
+	if detail.Port != nil && *detail.Port != 0 {
+		port = *detail.Port
+	}
+	return net.JoinHostPort(*detail.IP, strconv.Itoa(int(port)))
+}
+
 // initializeBroker fetches broker from circonus api and sets up broker tls config.
 func (c *Check) initializeBroker() error {
 	if c.apih == nil {
@@ -40,9 +191,33 @@ func (c *Check) initializeBroker() error {
 
 	c.broker = broker
 
+	if err := c.configureBrokerTLS(); err != nil {
+		return errors.Wrap(err, "setting broker tls config")
+	}
+
 	return nil
 }
 
+// configureBrokerTLS sets the broker tls config for metric submissions, from
+// the broker CA for https submission urls, nil (system roots) for http or the
+// public trap broker.
+func (c *Check) configureBrokerTLS() error {
+	c.brokerTLS = nil
+
+	u, err := url.Parse(c.bundle.Config["submission_url"])
+	if err != nil {
+		return errors.Wrap(err, "parsing submission url")
+	}
+	if u.Scheme != "https" {
+		return nil
+	}
+	if strings.Contains(u.Host, "api.circonus.com") {
+		return nil // api.circonus.com uses a public certificate, no tls config needed
+	}
+
+	return c.setBrokerTLSConfig()
+}
+
 // BrokerTLSConfig returns the broker tls configuration for metric submissions or nil if tls config not needed (e.g. public trap broker).
 func (c *Check) BrokerTLSConfig() (*tls.Config, error) {
 	c.Lock()
@@ -52,18 +227,6 @@ func (c *Check) BrokerTLSConfig() (*tls.Config, error) {
 		return nil, errors.New("invalid state (nil broker)")
 	}
 
-	if strings.Contains(c.bundle.Config["submission_url"], "api.circonus.com") {
-		return nil, nil // api.circonus.com uses a public certificate, no tls config needed
-	}
-
-	if c.brokerTLS != nil {
-		return c.brokerTLS, nil
-	}
-
-	if err := c.setBrokerTLSConfig(); err != nil {
-		return nil, errors.Wrap(err, "setting broker tls config")
-	}
-
 	return c.brokerTLS, nil
 }
 
@@ -0,0 +1,194 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/rs/zerolog"
+)
+
+func TestPickBroker(t *testing.T) {
+	t.Log("Testing pickBroker")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	defer l.Close()
+	_, p, _ := net.SplitHostPort(l.Addr().String())
+	n, _ := strconv.Atoi(p)
+	port := uint16(n)
+
+	// a port nothing is listening on
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	_, cp, _ := net.SplitHostPort(closed.Addr().String())
+	n, _ = strconv.Atoi(cp)
+	closedPort := uint16(n)
+	closed.Close()
+
+	ip := "127.0.0.1"
+	broker := func(cid, status string, port uint16, tags ...string) apiclient.Broker {
+		return apiclient.Broker{
+			CID:  cid,
+			Tags: tags,
+			Details: []apiclient.BrokerDetail{
+				{IP: &ip, Port: &port, Status: status, Modules: []string{"httptrap", "json"}},
+			},
+		}
+	}
+
+	brokers := []apiclient.Broker{
+		broker("/broker/1", "active", closedPort, "region:us-east"),
+		broker("/broker/2", "unprovisioned", port, "region:us-east"),
+		broker("/broker/3", "active", port, "region:us-west"),
+		broker("/broker/4", "active", port, "region:us-east", "env:prod"),
+	}
+
+	t.Log("\tno tags")
+	{
+		b, _, err := pickBroker(brokers, "httptrap", nil, time.Second)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if b.CID != "/broker/3" && b.CID != "/broker/4" {
+			t.Fatalf("expected reachable active broker, got %s", b.CID)
+		}
+	}
+
+	t.Log("\ttags")
+	{
+		b, _, err := pickBroker(brokers, "httptrap", []string{"region:us-east"}, time.Second)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if b.CID != "/broker/4" {
+			t.Fatalf("expected /broker/4, got %s", b.CID)
+		}
+	}
+
+	t.Log("\tunsupported module")
+	{
+		if _, _, err := pickBroker(brokers, "cloudwatch", nil, time.Second); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tno broker with tags")
+	{
+		if _, _, err := pickBroker(brokers, "httptrap", []string{"region:eu-west"}, time.Second); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestBrokerAddress(t *testing.T) {
+	t.Log("Testing brokerAddress")
+
+	ip := "10.0.0.1"
+	port := uint16(443)
+	host := "broker.example.com"
+
+	tests := []struct {
+		detail apiclient.BrokerDetail
+		expect string
+	}{
+		{apiclient.BrokerDetail{IP: &ip}, "10.0.0.1:43191"},
+		{apiclient.BrokerDetail{IP: &ip, Port: &port}, "10.0.0.1:443"},
+		{apiclient.BrokerDetail{IP: &ip, ExternalHost: &host, ExternalPort: 8443}, "broker.example.com:8443"},
+		{apiclient.BrokerDetail{}, ""},
+	}
+	for _, tt := range tests {
+		if got := brokerAddress(tt.detail); got != tt.expect {
+			t.Fatalf("expected (%s), got (%s)", tt.expect, got)
+		}
+	}
+}
+
+func TestConfigureBrokerTLS(t *testing.T) {
+	t.Log("Testing configureBrokerTLS")
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	ip := "127.0.0.1"
+	newCheck := func(submissionURL string) *Check {
+		return &Check{
+			config: &Config{BrokerCAFile: caFile},
+			bundle: &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": submissionURL}},
+			broker: &apiclient.Broker{Details: []apiclient.BrokerDetail{{IP: &ip, CN: "example.com"}}},
+			logger: zerolog.Nop(),
+		}
+	}
+
+	t.Log("https, submits with broker ca")
+	{
+		c := newCheck(ts.URL)
+		if err := c.configureBrokerTLS(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.brokerTLS == nil {
+			t.Fatal("expected broker tls config")
+		}
+		if c.brokerTLS.ServerName != "example.com" {
+			t.Fatalf("expected example.com, got (%s)", c.brokerTLS.ServerName)
+		}
+		if err := c.SubmitMetrics(bytes.NewBufferString(`{"a":{"_type":"n","_value":1}}`)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("https, system roots fail")
+	{
+		c := newCheck(ts.URL)
+		if err := c.SubmitMetrics(bytes.NewBufferString(`{"a":{"_type":"n","_value":1}}`)); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("http, no tls config")
+	{
+		c := newCheck("http://127.0.0.1:43191/module/httptrap/abc/secret")
+		if err := c.configureBrokerTLS(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.brokerTLS != nil {
+			t.Fatal("expected nil broker tls config")
+		}
+	}
+
+	t.Log("public trap broker, no tls config")
+	{
+		c := newCheck("https://api.circonus.com/module/httptrap/abc/secret")
+		if err := c.configureBrokerTLS(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.brokerTLS != nil {
+			t.Fatal("expected nil broker tls config")
+		}
+	}
+}
@@ -18,8 +18,10 @@ import (
 	"github.com/pkg/errors"
 )
 
-// initializeCheckBundle finds or creates a new check bundle.
-func (c *Check) initializeCheckBundle() error {
+// initializeCheckBundle finds or creates a new check bundle. An existing check
+// bundle is reconciled with the configuration if reconcile is true (i.e. not
+// when refreshing a check moved to a different broker).
+func (c *Check) initializeCheckBundle(reconcile bool) error {
 	if c.apih == nil {
 		return errors.New("invalid state (nil api client)")
 	}
@@ -35,14 +37,20 @@ func (c *Check) initializeCheckBundle() error {
 			return errors.Errorf("invalid check bundle (%s), not active", bundle.CID)
 		}
 
+		if reconcile {
+			bundle = c.reconcileCheckBundle(bundle)
+		}
 		c.bundle = bundle
 		return nil
 	}
 
-	bundle, err := c.findOrCreateCheckBundle()
+	bundle, created, err := c.findOrCreateCheckBundle()
 	if err != nil {
 		return errors.Wrap(err, "finding/creating check")
 	}
+	if !created && reconcile {
+		bundle = c.reconcileCheckBundle(bundle)
+	}
 
 	c.logger.Debug().Interface("check_bundle", bundle).Msg("using check bundle")
 	c.bundle = bundle
@@ -50,49 +58,136 @@ func (c *Check) initializeCheckBundle() error {
 	return nil
 }
 
-// findOrCreateCheckBundle searches for a check bundle based on target and display name.
-func (c *Check) findOrCreateCheckBundle() (*apiclient.CheckBundle, error) {
+// findOrCreateCheckBundle searches for a check bundle based on target and display name,
+// creating one if none is found (created is true).
+func (c *Check) findOrCreateCheckBundle() (bundle *apiclient.CheckBundle, created bool, err error) {
 	searchCriteria := apiclient.SearchQueryType(fmt.Sprintf(`(active:1)(type:"%s")(host:%s)`, c.checkType, c.config.ID))
 
 	bundles, err := c.apih.SearchCheckBundles(&searchCriteria, nil)
 	if err != nil {
-		return nil, errors.Wrapf(err, "searching for check (%s)", searchCriteria)
+		return nil, false, errors.Wrapf(err, "searching for check (%s)", searchCriteria)
 	}
 
 	if len(*bundles) == 0 {
-		return c.createCheckBundle()
+		bundle, err = c.createCheckBundle()
+		return bundle, err == nil, err
 	}
 
-	numActive := 0
-	checkIdx := -1
-	for idx, cb := range *bundles {
-		if cb.Status != checkStatusActive {
-			continue
-		}
-		numActive++
-		if checkIdx == -1 {
-			checkIdx = idx // first match
+	var active []apiclient.CheckBundle
+	for _, cb := range *bundles {
+		if cb.Status == checkStatusActive {
+			active = append(active, cb)
 		}
 	}
 
-	if numActive > 1 {
-		return nil, errors.Errorf("multiple active checks found (%d) matching (%s)", numActive, searchCriteria)
+	switch len(active) {
+	case 0:
+		bundle, err = c.createCheckBundle()
+		return bundle, err == nil, err
+	case 1:
+		return &active[0], false, nil
+	}
+
+	bundle, err = c.resolveMultipleActive(active)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "multiple active checks found (%d) matching (%s)", len(active), searchCriteria)
+	}
+	return bundle, false, nil
+}
+
+// resolveMultipleActive returns the check bundle to use when several active
+// check bundles match the instance (e.g. a check was cloned) using the
+// configured strategy (see pickCheckBundle). With the deactivate strategy,
+// the other check bundles are deactivated.
+func (c *Check) resolveMultipleActive(bundles []apiclient.CheckBundle) (*apiclient.CheckBundle, error) {
+	cids := make([]string, len(bundles))
+	for i, b := range bundles {
+		cids[i] = b.CID
+	}
+
+	idx, err := pickCheckBundle(bundles, c.config.MultipleActive)
+	if err != nil {
+		c.logger.Warn().Err(err).Strs("check_bundles", cids).Msg("multiple active check bundles found, see --check-multiple-active")
+		return nil, errors.Wrapf(err, "check bundles (%s)", strings.Join(cids, ","))
+	}
+
+	bundle := bundles[idx]
+	c.logger.Warn().
+		Strs("check_bundles", cids).
+		Str("using", bundle.CID).
+		Str("strategy", c.config.MultipleActive).
+		Msg("multiple active check bundles found")
+
+	if c.config.MultipleActive == MultipleActiveDeactivate {
+		for i := range bundles {
+			if i == idx {
+				continue
+			}
+			extra := bundles[i]
+			extra.Status = checkStatusDisabled
+			if _, err := c.apih.UpdateCheckBundle(&extra); err != nil {
+				c.logger.Warn().Err(err).Str("check_bundle", extra.CID).Msg("deactivating check bundle")
+				continue
+			}
+			c.logger.Warn().Str("check_bundle", extra.CID).Str("using", bundle.CID).Msg("deactivated check bundle")
+		}
 	}
 
-	bundle := (*bundles)[checkIdx]
 	return &bundle, nil
 }
 
+// pickCheckBundle returns the index of the check bundle to use from several
+// active ones. With the oldest strategy, the oldest check bundle. With notes,
+// the oldest check bundle created by the agent (its notes contain the release
+// name), an error if there are none. With deactivate, the same as notes, or
+// the oldest if none were created by the agent. Otherwise, an error.
+func pickCheckBundle(bundles []apiclient.CheckBundle, strategy string) (int, error) {
+	all := func(apiclient.CheckBundle) bool { return true }
+	byAgent := func(b apiclient.CheckBundle) bool {
+		return b.Notes != nil && strings.Contains(*b.Notes, release.NAME)
+	}
+
+	switch strategy {
+	case MultipleActiveOldest:
+		return oldestCheckBundle(bundles, all), nil
+	case MultipleActiveNotes, MultipleActiveDeactivate:
+		if idx := oldestCheckBundle(bundles, byAgent); idx >= 0 {
+			return idx, nil
+		}
+		if strategy == MultipleActiveDeactivate {
+			return oldestCheckBundle(bundles, all), nil
+		}
+		return -1, errors.Errorf("none with notes containing (%s)", release.NAME)
+	default:
+		return -1, errors.New("no strategy to choose one")
+	}
+}
+
+// oldestCheckBundle returns the index of the oldest check bundle for which
+// match returns true, -1 if there are none.
+func oldestCheckBundle(bundles []apiclient.CheckBundle, match func(apiclient.CheckBundle) bool) int {
+	idx := -1
+	for i, b := range bundles {
+		if !match(b) {
+			continue
+		}
+		if idx == -1 || b.Created < bundles[idx].Created {
+			idx = i
+		}
+	}
+	return idx
+}
+
 // createCheckBundle creates a new check bundle.
 func (c *Check) createCheckBundle() (*apiclient.CheckBundle, error) {
 	secret, err := makeSecret()
 	if err != nil {
 		secret = "myS3cr3t"
 	}
-	notes := fmt.Sprintf("%s-%s", release.NAME, release.VERSION)
-	broker := c.config.BrokerCID
-	if broker == "" {
-		broker = publicHTTPTrapBrokerCID
+	notes := checkNotes()
+	broker, err := c.selectBroker()
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting broker")
 	}
 
 	checkConfig := &apiclient.CheckBundle{
@@ -102,13 +197,13 @@ func (c *Check) createCheckBundle() (*apiclient.CheckBundle, error) {
 			"secret":         secret,
 		},
 		DisplayName:   c.config.DisplayName,
-		MetricFilters: checkMetricFilters,
+		MetricFilters: c.metricFilters(),
 		MetricLimit:   apiclicfg.DefaultCheckBundleMetricLimit,
 		Metrics:       []apiclient.CheckBundleMetric{},
 		Notes:         &notes,
 		Period:        60,
 		Status:        checkStatusActive,
-		Tags:          strings.Split(c.config.Tags, ","),
+		Tags:          checkTags(c.config.Tags),
 		Target:        c.config.ID,
 		Timeout:       10,
 		Type:          c.checkType,
@@ -0,0 +1,65 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+func TestPickCheckBundle(t *testing.T) {
+	t.Log("Testing pickCheckBundle")
+
+	agentNotes := release.NAME + "-v1.0.0"
+	cloneNotes := "cloned by hand"
+	bundles := []apiclient.CheckBundle{
+		{CID: "/check_bundle/3", Created: 300, Notes: &agentNotes},
+		{CID: "/check_bundle/1", Created: 100, Notes: &cloneNotes},
+		{CID: "/check_bundle/2", Created: 200, Notes: &agentNotes},
+	}
+	clones := []apiclient.CheckBundle{
+		{CID: "/check_bundle/5", Created: 500},
+		{CID: "/check_bundle/4", Created: 400, Notes: &cloneNotes},
+	}
+
+	tests := []struct {
+		bundles  []apiclient.CheckBundle
+		strategy string
+		expect   string
+	}{
+		{bundles, MultipleActiveOldest, "/check_bundle/1"},
+		{bundles, MultipleActiveNotes, "/check_bundle/2"},
+		{bundles, MultipleActiveDeactivate, "/check_bundle/2"},
+		{clones, MultipleActiveDeactivate, "/check_bundle/4"},
+	}
+	for _, tt := range tests {
+		idx, err := pickCheckBundle(tt.bundles, tt.strategy)
+		if err != nil {
+			t.Fatalf("expected no error for %s, got (%s)", tt.strategy, err)
+		}
+		if tt.bundles[idx].CID != tt.expect {
+			t.Fatalf("expected %s for %s, got %s", tt.expect, tt.strategy, tt.bundles[idx].CID)
+		}
+	}
+
+	t.Log("\tno agent check bundle")
+	{
+		if _, err := pickCheckBundle(clones, MultipleActiveNotes); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tno strategy")
+	{
+		for _, strategy := range []string{"", MultipleActiveError} {
+			if _, err := pickCheckBundle(bundles, strategy); err == nil {
+				t.Fatalf("expected error for (%s)", strategy)
+			}
+		}
+	}
+}
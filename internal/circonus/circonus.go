@@ -8,10 +8,12 @@ package circonus
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
 	apiclient "github.com/circonus-labs/go-apiclient"
@@ -22,44 +24,95 @@ import (
 
 // ServiceConfig defines the Circonus configuration for a cloud service to include.
 type ServiceConfig struct {
-	CID          string `json:"cid" toml:"cid" yaml:"cid"`                               // OPTIONAL, cid of specific check bundle to use
-	BrokerCID    string `json:"broker_cid" toml:"broker_cid" yaml:"broker_cid"`          // OPTIONAL, default public trap broker
-	App          string `json:"app" toml:"app" yaml:"app"`                               // REQUIRED
-	Key          string `json:"key" toml:"key" yaml:"key"`                               // REQUIRED
-	URL          string `json:"url" toml:"url" yaml:"url"`                               // DEFAULT 'https://api.circonus.com/v2/'
-	CAFile       string `json:"ca_file" toml:"ca_file" yaml:"ca_file"`                   // DEFAULT api.circonus.com uses a public certificate
-	Debug        bool   `json:"debug" toml:"debug" yaml:"debug"`                         // DEFAULT false - this is separate so that the global debug does not inundate logs with cgm debug messages from each cloud service metric collection client
-	TraceMetrics bool   `json:"trace_metrics" toml:"trace_metrics" yaml:"trace_metrics"` // DEFAULT false - output each metric as it is sent
+	CID           string         `json:"cid" toml:"cid" yaml:"cid"`                                  // OPTIONAL, cid of specific check bundle to use
+	BrokerCID     string         `json:"broker_cid" toml:"broker_cid" yaml:"broker_cid"`             // OPTIONAL, default lowest latency broker with broker_tags
+	BrokerTags    []string       `json:"broker_tags" toml:"broker_tags" yaml:"broker_tags"`          // OPTIONAL, tags a broker must have to be selected when broker_cid is not set (e.g. region:us-east)
+	App           string         `json:"app" toml:"app" yaml:"app"`                                  // REQUIRED
+	Key           string         `json:"key" toml:"key" yaml:"key"`                                  // REQUIRED
+	URL           string         `json:"url" toml:"url" yaml:"url"`                                  // DEFAULT 'https://api.circonus.com/v2/'
+	CAFile        string         `json:"ca_file" toml:"ca_file" yaml:"ca_file"`                      // DEFAULT api.circonus.com uses a public certificate - verifies both the API and the broker (e.g. private CA)
+	Debug         bool           `json:"debug" toml:"debug" yaml:"debug"`                            // DEFAULT false - this is separate so that the global debug does not inundate logs with cgm debug messages from each cloud service metric collection client
+	TraceMetrics  bool           `json:"trace_metrics" toml:"trace_metrics" yaml:"trace_metrics"`    // DEFAULT false - output each metric as it is sent
+	Compression   string         `json:"compression" toml:"compression" yaml:"compression"`          // DEFAULT none - compress metric submissions (none|gzip|deflate)
+	MetricFilters []MetricFilter `json:"metric_filters" toml:"metric_filters" yaml:"metric_filters"` // DEFAULT allow all - check bundle metric filters, applied by the broker in order
 }
 
 // Config options for a Circonus Check passed to New method.
 type Config struct {
-	ID            string         // a unique identifier, used to search for a check bundle
-	CheckBundleID string         // a specific check bundle cid to use
-	BrokerCID     string         // broker cid to use (default circonus public broker)
-	BrokerCAFile  string         // broker ca file
-	DisplayName   string         // display name to use for check bundle (when searching or creating)
-	Tags          string         // tags to add to a check when creating
-	APIKey        string         // Circonus API Token key
-	APIApp        string         // Circonus API Token app
-	APIURL        string         // Circonus API URL
-	APICAFile     string         // api ca file
-	Logger        zerolog.Logger // logging instance to use
-	Debug         bool           // turn on debugging messages
-	TraceMetrics  bool           // output each metric as it is sent
+	ID                 string         // a unique identifier, used to search for a check bundle
+	CheckBundleID      string         // a specific check bundle cid to use
+	BrokerCID          string         // broker cid to use (default selected, see selectBroker)
+	BrokerTags         []string       // tags a broker must have to be selected (when BrokerCID is not set)
+	BrokerCAFile       string         // broker ca file
+	DisplayName        string         // display name to use for check bundle (when searching or creating)
+	Tags               string         // tags to add to a check when creating
+	APIKey             string         // Circonus API Token key
+	APIApp             string         // Circonus API Token app
+	APIURL             string         // Circonus API URL
+	APICAFile          string         // api ca file
+	Logger             zerolog.Logger // logging instance to use
+	SpoolDir           string         // directory to spool failed submissions (disabled if empty)
+	SpoolMaxSize       int64          // maximum size of spooled submissions, in bytes
+	SubmitRetries      int            // number of times to retry a failed submission
+	SubmitTimeout      time.Duration  // timeout for a metric submission request (0 = no timeout)
+	SubmitIdleTimeout  time.Duration  // how long idle broker connections are kept for reuse (0 = no limit)
+	SubmitMaxConns     int            // maximum connections to the broker (0 = no limit)
+	SubmitMaxIdleConns int            // maximum idle connections kept to the broker for reuse
+	Compression        string         // compress metric submissions (none|gzip|deflate), default none
+	PipeSubmits        bool           // stream submissions to the broker while collecting (see NewSubmission)
+	DryRun             bool           // no Circonus API calls, submissions are written to DryRunOutput rather than the broker
+	DryRunOutput       string         // file dry run submissions are written to (default stdout)
+	MetricFilters      []MetricFilter // check bundle metric filters (default allow all, see metricFilters)
+	Reconcile          string         // existing check bundles are reconciled with the config (update|log|off), default update
+	MultipleActive     string         // how multiple active check bundles are resolved (error|oldest|notes|deactivate), default error
+	Debug              bool           // turn on debugging messages
+	TraceMetrics       bool           // output each metric as it is sent
+}
+
+// NewConfig returns the check Config for the Circonus configuration of a
+// service instance. The ca_file is used for both the API and the broker (e.g.
+// an on-premise deployment with a private CA). The check identity (ID,
+// DisplayName, Tags), Logger and the agent wide submission options are set
+// by the caller.
+func NewConfig(sc ServiceConfig) *Config {
+	return &Config{
+		CheckBundleID: sc.CID,
+		BrokerCID:     sc.BrokerCID,
+		BrokerTags:    sc.BrokerTags,
+		BrokerCAFile:  sc.CAFile,
+		APIKey:        sc.Key,
+		APIApp:        sc.App,
+		APIURL:        sc.URL,
+		APICAFile:     sc.CAFile,
+		Compression:   sc.Compression,
+		Debug:         sc.Debug,
+		TraceMetrics:  sc.TraceMetrics,
+		MetricFilters: sc.MetricFilters,
+	}
 }
 
 // Check defines a Circonus check for a circonus-cloud-agent service.
 type Check struct {
-	apih            *apiclient.API
-	config          *Config
-	broker          *apiclient.Broker
-	brokerTLS       *tls.Config
-	bundle          *apiclient.CheckBundle
-	metricTypeRx    *regexp.Regexp
-	errorMetricName string
-	checkType       string
-	logger          zerolog.Logger
+	apih              *apiclient.API
+	config            *Config
+	broker            *apiclient.Broker
+	brokerTLS         *tls.Config
+	client            *http.Client // metric submissions, see httpClient
+	clientTLS         *tls.Config  // broker tls config client was built with
+	bundle            *apiclient.CheckBundle
+	lastRefresh       time.Time // last automatic refresh, see refreshOnError
+	spool             *spool
+	dryRun            *dryRunWriter // destination for submissions in dry run mode (see Config.DryRun)
+	metricTypeRx      *regexp.Regexp
+	statsMu           sync.Mutex // protects stats, updated outside of the check lock (e.g. WriteMetricSample)
+	stats             Stats
+	errorMetricName   string
+	statsMetricPrefix string
+	checkType         string
+	svcID             string
+	consecutive5xx    int
+	logger            zerolog.Logger
+	closed            bool // see Close
 	sync.Mutex
 }
 
@@ -91,6 +144,36 @@ const (
 	// MetricTypeString reconnoiter.
 	MetricTypeString = "s"
 
+	// ReconcileUpdate existing check bundles are updated to match the config.
+	ReconcileUpdate = "update"
+
+	// ReconcileLog differences between existing check bundles and the config are only logged.
+	ReconcileLog = "log"
+
+	// ReconcileOff existing check bundles are used as is.
+	ReconcileOff = "off"
+
+	// MultipleActiveError instances with multiple active check bundles fail to start.
+	MultipleActiveError = "error"
+
+	// MultipleActiveOldest the oldest of multiple active check bundles is used.
+	MultipleActiveOldest = "oldest"
+
+	// MultipleActiveNotes the oldest of multiple active check bundles created by the agent (notes) is used.
+	MultipleActiveNotes = "notes"
+
+	// MultipleActiveDeactivate one of multiple active check bundles is used (see MultipleActiveNotes), the others are deactivated.
+	MultipleActiveDeactivate = "deactivate"
+
+	// CompressionNone metric submissions are not compressed.
+	CompressionNone = "none"
+
+	// CompressionGzip metric submissions are gzip compressed.
+	CompressionGzip = "gzip"
+
+	// CompressionDeflate metric submissions are deflate (zlib) compressed.
+	CompressionDeflate = "deflate"
+
 	// NOTE: max tags and metric name len are enforced here so that
 	// details on which metric(s) can be logged. Otherwise, any
 	// metric(s) exceeding the limits are rejected by the broker
@@ -107,6 +190,7 @@ const (
 var (
 	publicHTTPTrapBrokerCID = "/broker/35"
 	checkStatusActive       = "active"
+	checkStatusDisabled     = "disabled"
 	checkMetricFilters      = [][]string{
 		{"deny", "^$", ""},
 		{"allow", "^.+$", ""},
@@ -114,7 +198,9 @@ var (
 )
 
 // NewCheck creates a new Circonus check instance based on the Config options passed to
-// initialize the Circonus API, check and broker.
+// initialize the Circonus API, check and broker. In dry run mode (Config.DryRun)
+// no Circonus API calls are made, the check only writes submissions to the dry
+// run output.
 func NewCheck(svcID string, cfg *Config) (*Check, error) {
 	{ // verify service id
 		found, err := regexp.MatchString(`^(aws|azure|gcp)$`, svcID)
@@ -130,10 +216,29 @@ func NewCheck(svcID string, cfg *Config) (*Check, error) {
 		return nil, errors.New("invalid config (nil)")
 	}
 
+	switch cfg.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionDeflate:
+	default:
+		return nil, errors.Errorf("invalid compression (%s), expected none, gzip, or deflate", cfg.Compression)
+	}
+
+	switch cfg.Reconcile {
+	case "", ReconcileUpdate, ReconcileLog, ReconcileOff:
+	default:
+		return nil, errors.Errorf("invalid reconcile mode (%s), expected update, log, or off", cfg.Reconcile)
+	}
+
+	switch cfg.MultipleActive {
+	case "", MultipleActiveError, MultipleActiveOldest, MultipleActiveNotes, MultipleActiveDeactivate:
+	default:
+		return nil, errors.Errorf("invalid multiple active strategy (%s), expected error, oldest, notes, or deactivate", cfg.MultipleActive)
+	}
+
 	c := &Check{
-		config:          cfg,
-		errorMetricName: strings.ReplaceAll(release.NAME, "-", "_") + "_errors", // TBD: may become a config option
-		logger:          cfg.Logger.With().Str("pkg", "check").Logger(),
+		config:            cfg,
+		errorMetricName:   strings.ReplaceAll(release.NAME, "-", "_") + "_errors", // TBD: may become a config option
+		statsMetricPrefix: strings.ReplaceAll(release.NAME, "-", "_") + "_",
+		logger:            cfg.Logger.With().Str("pkg", "check").Logger(),
 		metricTypeRx: regexp.MustCompile("^[" + strings.Join([]string{
 			MetricTypeInt32,
 			MetricTypeUint32,
@@ -143,13 +248,25 @@ func NewCheck(svcID string, cfg *Config) (*Check, error) {
 			MetricTypeString,
 		}, "") + "]$"),
 		checkType: "httptrap:cloud_agent_" + svcID,
+		svcID:     svcID,
+	}
+
+	if cfg.DryRun {
+		w, err := dryRunOutput(cfg.DryRunOutput)
+		if err != nil {
+			return nil, err
+		}
+		c.dryRun = w
+		c.config.PipeSubmits = false // submissions are buffered and written to the dry run output
+		c.logger.Info().Msg("dry run, no check bundle and no metric submissions")
+		return c, nil
 	}
 
 	if err := c.initAPI(); err != nil {
 		return nil, errors.Wrap(err, "initializing Circonus API")
 	}
 
-	if err := c.initializeCheckBundle(); err != nil {
+	if err := c.initializeCheckBundle(true); err != nil {
 		return nil, errors.Wrap(err, "initializing check")
 	}
 	if _, ok := c.bundle.Config[apiclicfg.SubmissionURL]; !ok {
@@ -160,9 +277,36 @@ func NewCheck(svcID string, cfg *Config) (*Check, error) {
 		return nil, errors.Wrap(err, "initializing broker")
 	}
 
+	if cfg.SpoolDir != "" {
+		s, err := newSpool(cfg.SpoolDir, cfg.ID, cfg.SpoolMaxSize, c.logger)
+		if err != nil {
+			c.logger.Warn().Err(err).Msg("submission spool disabled")
+		} else {
+			c.spool = s
+		}
+	}
+
 	return c, nil
 }
 
+// Close releases the check once the service instance using it is stopped,
+// its series are removed from the agent telemetry (see telemetry.Handler)
+// and its submission connections are closed. Submissions still in progress
+// (e.g. from a collection being canceled) complete without keeping
+// connections open.
+func (c *Check) Close() {
+	c.deleteTelemetry()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.closed = true
+	if c.client != nil {
+		c.client.CloseIdleConnections()
+		c.client = nil // rebuilt without keep-alives if needed
+	}
+}
+
 // RefreshCheck fetches a new copy of the check bundle and broker from Circonus API.
 // Primary use-case is when a check is moved from one broker to another, metric
 // submits will fail. Refreshing the check bundle will obtain the new submission
@@ -171,19 +315,36 @@ func (c *Check) RefreshCheck() error {
 	c.Lock()
 	defer c.Unlock()
 
+	return c.refresh()
+}
+
+// refresh the check bundle and broker, the caller must hold the check lock.
+// If the refresh fails, the current check bundle and broker are retained.
+func (c *Check) refresh() error {
 	if c.apih == nil {
 		return errors.New("invalid state (nil api client)")
 	}
 
+	bundle, broker, brokerTLS := c.bundle, c.broker, c.brokerTLS
+	restore := func() {
+		c.bundle, c.broker, c.brokerTLS = bundle, broker, brokerTLS
+	}
+
 	c.bundle = nil
 	c.broker = nil
 	c.brokerTLS = nil
 
-	if err := c.initializeCheckBundle(); err != nil {
+	if err := c.initializeCheckBundle(false); err != nil {
+		restore()
 		return errors.Wrap(err, "refreshing check")
 	}
+	if _, ok := c.bundle.Config[apiclicfg.SubmissionURL]; !ok {
+		restore()
+		return errors.Errorf("refreshing check, check bundle invalid, missing submission url")
+	}
 
 	if err := c.initializeBroker(); err != nil {
+		restore()
 		return errors.Wrap(err, "refreshing broker")
 	}
 
@@ -0,0 +1,54 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpClient returns the check's long-lived http client used for metric
+// submissions. Connections are kept alive and reused between submissions.
+// The client is rebuilt only when the broker tls config changes (e.g. the
+// check was refreshed after moving to a different broker) or the check is
+// closed. The caller must hold the check lock.
+func (c *Check) httpClient() *http.Client {
+	if c.client != nil && c.clientTLS == c.brokerTLS {
+		return c.client
+	}
+
+	if c.client != nil {
+		c.logger.Debug().Msg("broker tls config changed, rebuilding submission http client")
+		c.client.CloseIdleConnections()
+	}
+
+	maxIdle := c.config.SubmitMaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = http.DefaultMaxIdleConnsPerHost
+	}
+
+	c.client = &http.Client{
+		Timeout: c.config.SubmitTimeout, // 0 = no timeout
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig:     c.brokerTLS,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxConnsPerHost:     c.config.SubmitMaxConns, // 0 = no limit
+			MaxIdleConns:        maxIdle,
+			MaxIdleConnsPerHost: maxIdle,
+			IdleConnTimeout:     c.config.SubmitIdleTimeout, // 0 = no limit
+			DisableKeepAlives:   c.closed,
+		},
+	}
+	c.clientTLS = c.brokerTLS
+
+	return c.client
+}
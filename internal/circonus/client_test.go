@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/rs/zerolog"
+)
+
+func TestHTTPClient(t *testing.T) {
+	t.Log("Testing httpClient")
+
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c := &Check{
+		config: &Config{SubmitMaxConns: 1, SubmitMaxIdleConns: 1},
+		bundle: &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": ts.URL}},
+		logger: zerolog.Nop(),
+	}
+
+	t.Log("reused")
+	{
+		client := c.httpClient()
+		for i := 0; i < 5; i++ {
+			if err := c.SubmitMetrics(bytes.NewBufferString(`{"a":{"_type":"n","_value":1}}`)); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+		if c.httpClient() != client {
+			t.Fatal("expected same client")
+		}
+		if n := atomic.LoadInt32(&conns); n != 1 {
+			t.Fatalf("expected 1 connection, got %d", n)
+		}
+	}
+
+	t.Log("rebuilt, broker tls changed")
+	{
+		client := c.httpClient()
+		c.brokerTLS = &tls.Config{MinVersion: tls.VersionTLS12} //nolint:gosec
+		if c.httpClient() == client {
+			t.Fatal("expected new client")
+		}
+	}
+
+	t.Log("closed, no keep-alives")
+	{
+		c.Close()
+		if c.client != nil {
+			t.Fatal("expected client released")
+		}
+		tr, ok := c.httpClient().Transport.(*http.Transport)
+		if !ok || !tr.DisableKeepAlives {
+			t.Fatal("expected client without keep-alives")
+		}
+	}
+}
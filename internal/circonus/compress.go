@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// compress encodes payload using the configured compression. Returns the
+// request body and the Content-Encoding to use, which is empty if the
+// payload is not compressed.
+func (c *Check) compress(payload []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch c.config.Compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionDeflate:
+		w = zlib.NewWriter(&buf) // http 'deflate' is zlib format (rfc 1950)
+	default:
+		return payload, "", nil
+	}
+
+	if _, err := w.Write(payload); err != nil {
+		return nil, "", errors.Wrapf(err, "compressing payload (%s)", c.config.Compression)
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", errors.Wrapf(err, "compressing payload (%s)", c.config.Compression)
+	}
+
+	if len(payload) > 0 {
+		c.logger.Debug().
+			Str("encoding", c.config.Compression).
+			Int("bytes", len(payload)).
+			Int("compressed_bytes", buf.Len()).
+			Float64("ratio", float64(len(payload))/float64(buf.Len())).
+			Msg("compressed payload")
+	}
+
+	return buf.Bytes(), c.config.Compression, nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/rs/zerolog"
+)
+
+func TestCompression(t *testing.T) {
+	t.Log("Testing compression")
+
+	payload := bytes.Repeat([]byte(`{"metric|ST[b\"Y29sbGVjdG9y\":b\"dGVzdA==\"]":{"_type":"n","_value":1}}`+"\n"), 100)
+
+	var encoding string
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		var rdr io.Reader = r.Body
+		switch encoding {
+		case "gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rdr = zr
+		case "deflate":
+			zr, err := zlib.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rdr = zr
+		}
+		received, _ = io.ReadAll(rdr)
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	for _, compression := range []string{"", CompressionNone, CompressionGzip, CompressionDeflate} {
+		t.Logf("compression (%s)", compression)
+		c := &Check{
+			config: &Config{Compression: compression},
+			bundle: &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": ts.URL}},
+			logger: zerolog.Nop(),
+		}
+		if err := c.SubmitMetrics(bytes.NewReader(payload)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		expected := compression
+		if compression == CompressionNone {
+			expected = ""
+		}
+		if encoding != expected {
+			t.Fatalf("expected content encoding (%s), got (%s)", expected, encoding)
+		}
+		if !bytes.Equal(received, payload) {
+			t.Fatal("received payload does not match")
+		}
+		if expected != "" && c.Stats().BytesSubmitted >= uint64(len(payload)) {
+			t.Fatalf("expected compressed size < %d, got %d", len(payload), c.Stats().BytesSubmitted)
+		}
+	}
+}
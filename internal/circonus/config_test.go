@@ -0,0 +1,45 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Log("Testing NewConfig")
+
+	sc := ServiceConfig{
+		CID:          "/check_bundle/123",
+		BrokerCID:    "/broker/456",
+		BrokerTags:   []string{"region:us-east"},
+		App:          "app",
+		Key:          "key",
+		URL:          "https://api.example.com/v2/",
+		CAFile:       "/etc/ca.crt",
+		Debug:        true,
+		TraceMetrics: true,
+		Compression:  CompressionGzip,
+	}
+	cfg := NewConfig(sc)
+	expect := Config{
+		CheckBundleID: sc.CID,
+		BrokerCID:     sc.BrokerCID,
+		BrokerTags:    sc.BrokerTags,
+		BrokerCAFile:  sc.CAFile,
+		APIKey:        sc.Key,
+		APIApp:        sc.App,
+		APIURL:        sc.URL,
+		APICAFile:     sc.CAFile,
+		Compression:   sc.Compression,
+		Debug:         true,
+		TraceMetrics:  true,
+	}
+	if !reflect.DeepEqual(*cfg, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, *cfg)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// dryRunWriter is a destination for dry run submissions (see Config.DryRun),
+// shared by the checks writing to the same output. Each submission is
+// written with a single write so submissions from different checks are not
+// interleaved.
+type dryRunWriter struct {
+	w io.Writer
+	sync.Mutex
+}
+
+var (
+	dryRunMu      sync.Mutex
+	dryRunOutputs = map[string]*dryRunWriter{}
+)
+
+// dryRunOutput returns the dry run destination for path, stdout if path is empty.
+func dryRunOutput(path string) (*dryRunWriter, error) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	if w, ok := dryRunOutputs[path]; ok {
+		return w, nil
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening dry run output")
+		}
+		w = f
+	}
+
+	dw := &dryRunWriter{w: w}
+	dryRunOutputs[path] = dw
+	return dw, nil
+}
+
+// CloseDryRunOutputs flushes and closes the dry run output files, once the
+// dry run collection is complete. Stdout is not closed.
+func CloseDryRunOutputs() error {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	var firstErr error
+	for path, dw := range dryRunOutputs {
+		delete(dryRunOutputs, path)
+		f, ok := dw.w.(*os.File)
+		if !ok || f == os.Stdout {
+			continue
+		}
+		dw.Lock()
+		err := f.Sync()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		dw.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "closing dry run output (%s)", path)
+		}
+	}
+
+	return firstErr
+}
+
+// writeDryRun writes a submission payload to the dry run output, in place
+// of submitting it to the broker.
+func (c *Check) writeDryRun(metricSrc io.Reader) error {
+	if metricSrc == nil {
+		return errors.New("invalid metric source (nil)")
+	}
+
+	payload, err := io.ReadAll(metricSrc)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+
+	c.dryRun.Lock()
+	defer c.dryRun.Unlock()
+	if _, err := c.dryRun.w.Write(payload); err != nil {
+		return errors.Wrap(err, "writing dry run submission")
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Log("Testing dry run")
+
+	out := filepath.Join(t.TempDir(), "dryrun.json")
+
+	// no api url/key, would fail if the Circonus API were called
+	c, err := NewCheck("aws", &Config{ID: "test", DryRun: true, DryRunOutput: out, PipeSubmits: true, SpoolDir: t.TempDir(), Logger: zerolog.Nop()})
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if c.apih != nil || c.bundle != nil || c.spool != nil {
+		t.Fatal("expected no api client, check bundle, or spool")
+	}
+
+	s := c.NewSubmission()
+	if err := s.WriteMetricSample("foo", MetricTypeFloat64, 1.5, nil); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := s.Submit(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := c.SubmitMetrics(strings.NewReader(`{"bar":{"_type":"s","_value":"baz"}}` + "\n")); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	if err := CloseDryRunOutputs(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if len(dryRunOutputs) != 0 {
+		t.Fatal("expected dry run outputs to be closed")
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d (%s)", len(lines), data)
+	}
+	if lines[0] != `{"foo":{"_type":"n","_value":1.5}}` {
+		t.Fatalf("unexpected sample (%s)", lines[0])
+	}
+	if !strings.Contains(lines[1], `"bar"`) {
+		t.Fatalf("unexpected sample (%s)", lines[1])
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MetricFilterAllow metrics matching the filter are accepted by the broker.
+	MetricFilterAllow = "allow"
+
+	// MetricFilterDeny metrics matching the filter are rejected by the broker.
+	MetricFilterDeny = "deny"
+)
+
+// MetricFilter defines a check bundle metric filter. The broker applies the
+// filters in order, the first one matching a metric decides whether it is
+// accepted (allow) or rejected (deny).
+type MetricFilter struct {
+	Type    string `json:"type" toml:"type" yaml:"type"`          // REQUIRED (allow|deny)
+	Filter  string `json:"filter" toml:"filter" yaml:"filter"`    // DEFAULT any metric - regex matched against metric names
+	Tags    string `json:"tags" toml:"tags" yaml:"tags"`          // OPTIONAL tag query, metrics must also match (e.g. and(env:prod))
+	Comment string `json:"comment" toml:"comment" yaml:"comment"` // OPTIONAL
+}
+
+// agentMetricFilter allows the metrics about the agent itself (errors and
+// telemetry), added ahead of configured metric filters.
+var agentMetricFilter = MetricFilter{
+	Type:    MetricFilterAllow,
+	Filter:  "^" + regexp.QuoteMeta(strings.ReplaceAll(release.NAME, "-", "_")+"_"),
+	Comment: "agent metrics",
+}
+
+// Validate verifies the metric filter type and regex.
+func (f MetricFilter) Validate() error {
+	if f.Type != MetricFilterAllow && f.Type != MetricFilterDeny {
+		return errors.Errorf("invalid type (%s), expected allow or deny", f.Type)
+	}
+	if _, err := regexp.Compile(f.Filter); err != nil {
+		return errors.Errorf("invalid filter (%s): %s", f.Filter, err)
+	}
+	if f.Tags != "" {
+		if err := validateTagQuery(f.Tags); err != nil {
+			return errors.Errorf("invalid tag query (%s): %s", f.Tags, err)
+		}
+	}
+	return nil
+}
+
+// validateTagQuery verifies a tag query, either a category:value term (e.g.
+// env:prod) or an and, or, or not operator applied to comma separated tag
+// queries in parentheses (e.g. and(env:prod,not(team:ops))).
+func validateTagQuery(query string) error {
+	end, err := parseTagQuery(query, 0)
+	if err != nil {
+		return err
+	}
+	if rest := strings.TrimSpace(query[end:]); rest != "" {
+		return errors.Errorf("unexpected (%s) at %d", rest, end)
+	}
+	return nil
+}
+
+// parseTagQuery parses the tag query starting at pos, returns the position
+// following it. Quoted (b"...") and regex (/.../) values may contain
+// parentheses and commas.
+func parseTagQuery(query string, pos int) (int, error) {
+	end := pos
+	for end < len(query) {
+		ch := query[end]
+		if (ch == '"' || ch == '/') && strings.HasSuffix(strings.TrimSuffix(query[pos:end], "b"), ":") {
+			n := strings.IndexByte(query[end+1:], ch)
+			if n < 0 {
+				return 0, errors.Errorf("unterminated %c at %d", ch, end)
+			}
+			end += n + 2
+			continue
+		}
+		if ch == '(' || ch == ')' || ch == ',' {
+			break
+		}
+		end++
+	}
+	token := strings.TrimSpace(query[pos:end])
+
+	if end == len(query) || query[end] != '(' {
+		if i := strings.Index(token, ":"); i <= 0 {
+			return 0, errors.Errorf("invalid tag (%s) at %d, expected category:value", token, pos)
+		}
+		return end, nil
+	}
+
+	op := strings.ToLower(token)
+	if op != "and" && op != "or" && op != "not" {
+		return 0, errors.Errorf("unknown operator (%s) at %d, expected and, or, or not", token, pos)
+	}
+	args := 0
+	pos = end + 1
+	for {
+		next, err := parseTagQuery(query, pos)
+		if err != nil {
+			return 0, err
+		}
+		args++
+		for next < len(query) && query[next] == ' ' {
+			next++
+		}
+		if next == len(query) {
+			return 0, errors.Errorf("missing ) for %s at %d", op, end)
+		}
+		if query[next] == ')' {
+			pos = next + 1
+			break
+		}
+		if query[next] != ',' {
+			return 0, errors.Errorf("unexpected (%c) at %d", query[next], next)
+		}
+		pos = next + 1
+	}
+	if op == "not" && args != 1 {
+		return 0, errors.Errorf("not takes a single tag query, got %d", args)
+	}
+	return pos, nil
+}
+
+// bundleFilter returns the filter in the check bundle metric_filters format,
+// [type, regex, comment] or, with a tag query, [type, regex, "tags", query, comment].
+func (f MetricFilter) bundleFilter() []string {
+	filter := f.Filter
+	if filter == "" {
+		filter = "^.+$"
+	}
+	if f.Tags != "" {
+		return []string{f.Type, filter, "tags", f.Tags, f.Comment}
+	}
+	return []string{f.Type, filter, f.Comment}
+}
+
+// metricFilters returns the check bundle metric filters. Without configured
+// metric filters all metrics are allowed. Otherwise, the agent's own metrics
+// are allowed, followed by the configured filters.
+func (c *Check) metricFilters() [][]string {
+	if len(c.config.MetricFilters) == 0 {
+		return checkMetricFilters
+	}
+	filters := [][]string{agentMetricFilter.bundleFilter()}
+	for _, f := range c.config.MetricFilters {
+		filters = append(filters, f.bundleFilter())
+	}
+	return filters
+}
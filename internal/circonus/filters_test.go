@@ -0,0 +1,93 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetricFilters(t *testing.T) {
+	t.Log("Testing metricFilters")
+
+	t.Log("\tdefault")
+	{
+		c := &Check{config: &Config{}}
+		if !reflect.DeepEqual(c.metricFilters(), checkMetricFilters) {
+			t.Fatalf("expected allow all, got %v", c.metricFilters())
+		}
+	}
+
+	t.Log("\tconfigured")
+	{
+		c := &Check{config: &Config{MetricFilters: []MetricFilter{
+			{Type: MetricFilterDeny, Filter: "^debug_"},
+			{Type: MetricFilterAllow, Tags: "and(env:prod)", Comment: "prod"},
+			{Type: MetricFilterDeny},
+		}}}
+		expect := [][]string{
+			{"allow", "^circonus_cloud_agent_", "agent metrics"},
+			{"deny", "^debug_", ""},
+			{"allow", "^.+$", "tags", "and(env:prod)", "prod"},
+			{"deny", "^.+$", ""},
+		}
+		if got := c.metricFilters(); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("expected %v, got %v", expect, got)
+		}
+		if !sameMetricFilters(c.metricFilters(), [][]string{{"allow", "^circonus_cloud_agent_"}, {"deny", "^debug_"}, {"allow", "^.+$", "tags", "and(env:prod)"}, {"deny", "^.+$"}}) {
+			t.Fatal("expected filters without comments to be the same")
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		for _, f := range []MetricFilter{{Type: "accept"}, {Type: MetricFilterAllow, Filter: "(["}, {Type: MetricFilterAllow, Tags: "and(env:prod"}} {
+			if err := f.Validate(); err == nil {
+				t.Fatalf("expected error for %+v", f)
+			}
+		}
+	}
+}
+
+func TestValidateTagQuery(t *testing.T) {
+	t.Log("Testing validateTagQuery")
+
+	t.Log("\tvalid")
+	{
+		for _, q := range []string{
+			"env:prod",
+			"and(env:prod)",
+			"and(env:prod, not(team:ops))",
+			"or(env:prod,and(region:us-east-1,team:ops))",
+			`env:b"cHJvZCgxKQ=="`,
+			"env:/^(prod|staging)$/",
+		} {
+			if err := validateTagQuery(q); err != nil {
+				t.Fatalf("expected no error for (%s), got (%s)", q, err)
+			}
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		for _, q := range []string{
+			"",
+			"prod",
+			":prod",
+			"and(env:prod",
+			"and(env:prod))",
+			"and()",
+			"xor(env:prod)",
+			"not(env:prod,team:ops)",
+			"and(env:prod)team:ops",
+			"env:/^(prod",
+		} {
+			if err := validateTagQuery(q); err == nil {
+				t.Fatalf("expected error for (%s)", q)
+			}
+		}
+	}
+}
@@ -10,8 +10,9 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net"
+	"math/rand"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -19,11 +20,20 @@ import (
 	"github.com/pkg/errors"
 )
 
-// SubmitMetrics to Circonus check.
+// SubmitMetrics to Circonus check. Failed submissions are retried with
+// exponential backoff (Config.SubmitRetries). If the broker is still not
+// reachable after retrying, and a spool is configured (Config.SpoolDir), the
+// payload is spooled and replayed after the next successful submission.
+// A spooled payload is not considered an error. In dry run mode (see
+// Config.DryRun) the payload is written to the dry run output instead.
 func (c *Check) SubmitMetrics(metricSrc io.Reader) error {
 	c.Lock()
 	defer c.Unlock()
 
+	if c.dryRun != nil {
+		return c.writeDryRun(metricSrc)
+	}
+
 	if c.bundle == nil {
 		return errors.New("invalid state (nil check bundle)")
 	}
@@ -32,102 +42,213 @@ func (c *Check) SubmitMetrics(metricSrc io.Reader) error {
 		return errors.New("invalid metric source (nil)")
 	}
 
-	subURL := ""
-	if surl, found := c.bundle.Config["submission_url"]; found {
-		subURL = surl
-	} else {
+	if _, found := c.bundle.Config["submission_url"]; !found {
 		return errors.New("invalid check bundle, no submission url")
 	}
 
-	var client *http.Client
-
-	if c.brokerTLS != nil {
-		client = &http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-					DualStack: true,
-				}).DialContext,
-				TLSClientConfig:     c.brokerTLS,
-				TLSHandshakeTimeout: 10 * time.Second,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 2,
-				DisableCompression:  false,
-			},
+	// the payload is buffered so it can be retried, and spooled if
+	// the submission still fails
+	src, traceDone := c.traceMetrics(metricSrc, "Submitted data")
+	mbuff, err := io.ReadAll(src)
+	traceDone()
+	if err != nil {
+		return err
+	}
+
+	if err := c.submitWithRetry(mbuff); err != nil {
+		if pr, isPipeReader := metricSrc.(*io.PipeReader); isPipeReader {
+			if cerr := pr.Close(); cerr != nil {
+				c.logger.Warn().Err(cerr).Msg("closing pipe reader")
+			}
 		}
-	} else {
-		client = &http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-					DualStack: true,
-				}).DialContext,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 2,
-				DisableCompression:  false,
-			},
+		var serr *submitError
+		if c.spool == nil || !errors.As(err, &serr) || !serr.retryable() {
+			return errors.Wrap(err, "submitting metrics")
+		}
+		if spoolErr := c.spool.add(mbuff); spoolErr != nil {
+			c.logger.Error().Err(spoolErr).Msg("spooling failed submission")
+			return errors.Wrap(err, "submitting metrics")
 		}
+		c.logger.Warn().Err(err).Int("bytes", len(mbuff)).Msg("submission failed, payload spooled for later delivery")
+		return nil
 	}
 
-	// for debugging only
-	mbuff, err := io.ReadAll(metricSrc)
-	if err != nil {
-		return err
+	if c.spool != nil {
+		sent, err := c.spool.replay(c.submit)
+		if sent > 0 {
+			c.logger.Info().Int("payloads", sent).Msg("replayed spooled submissions")
+		}
+		if err != nil {
+			c.logger.Warn().Err(err).Msg("replaying spooled submissions")
+		}
 	}
-	if e := c.logger.Debug(); e.Enabled() {
-		if c.config.TraceMetrics {
-			e.Msg("Submitted data")
-			fmt.Printf("\n===BEGIN(%d)\n%s\n===END\n", time.Now().UTC().UnixNano(), mbuff)
+
+	return nil
+}
+
+// submitError is returned by submit, it includes the http status code of
+// the response, or 0 if there was no response from the broker.
+type submitError struct {
+	err        error
+	status     string
+	statusCode int
+}
+
+func (e *submitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return "broker response " + e.status
+}
+
+func (e *submitError) Unwrap() error {
+	return e.err
+}
+
+// retryable returns true if the failure is potentially transient (no response,
+// request timeout, rate limited, or broker error).
+func (e *submitError) retryable() bool {
+	switch {
+	case e.statusCode == 0:
+		return true
+	case e.statusCode == http.StatusRequestTimeout:
+		return true
+	case e.statusCode == http.StatusTooManyRequests:
+		return true
+	case e.statusCode >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	submitRetryMinDelay = 1 * time.Second
+	submitRetryMaxDelay = 30 * time.Second
+)
+
+// submitWithRetry submits payload, retrying transient failures with
+// exponential backoff and jitter. If a failure indicates the check has been
+// moved to a different broker, the check is refreshed and the payload
+// resubmitted (see refreshOnError). The caller must hold the check lock, it
+// is released while waiting to retry so other submissions are not blocked.
+func (c *Check) submitWithRetry(payload []byte) error {
+	var err error
+	for attempt := 0; attempt <= c.config.SubmitRetries; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(attempt)
+			c.logger.Warn().Err(err).Int("attempt", attempt).Int("max_retries", c.config.SubmitRetries).Str("delay", delay.String()).Msg("retrying submission")
+			c.Unlock()
+			time.Sleep(delay)
+			c.Lock()
+		}
+		err = c.submit(payload)
+		if err != nil && c.refreshOnError(err) {
+			err = c.submit(payload)
+		}
+		if err == nil {
+			return nil
+		}
+		var serr *submitError
+		if errors.As(err, &serr) && !serr.retryable() {
+			return err
 		}
 	}
-	req, err := http.NewRequestWithContext(context.Background(), "PUT", subURL, bytes.NewReader(mbuff))
-	// return to this one when debugging submissions is complete
-	// req, err := http.NewRequest("PUT", subURL, metricSrc)
+	return err
+}
+
+// traceMetrics returns src, copied to stdout as it is read when metric
+// tracing (Config.TraceMetrics) and debug logging are enabled, and a func to
+// call once src has been read.
+func (c *Check) traceMetrics(src io.Reader, msg string) (io.Reader, func()) {
+	e := c.logger.Debug()
+	if !e.Enabled() || !c.config.TraceMetrics {
+		return src, func() {}
+	}
+	e.Msg(msg)
+	fmt.Printf("\n===BEGIN(%d)\n", time.Now().UTC().UnixNano())
+	return io.TeeReader(src, os.Stdout), func() { fmt.Printf("\n===END\n") }
+}
+
+// retryDelay returns the backoff delay for a retry attempt (1..n), doubling
+// from submitRetryMinDelay up to submitRetryMaxDelay, with the upper half
+// randomized so that instances do not retry in lock step.
+func retryDelay(attempt int) time.Duration {
+	delay := submitRetryMaxDelay
+	if attempt < 16 {
+		if d := submitRetryMinDelay << uint(attempt-1); d < submitRetryMaxDelay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)) //nolint:gosec
+}
+
+// submit makes a single attempt to PUT payload to the check submission url.
+func (c *Check) submit(payload []byte) error {
+	subURL := c.bundle.Config["submission_url"]
+
+	body, encoding, err := c.compress(payload)
+	if err != nil {
+		return err
+	}
+
+	respBody, err := c.put(c.httpClient(), subURL, bytes.NewReader(body), encoding, func() int { return len(body) })
 	if err != nil {
 		return err
 	}
+
+	c.logger.Debug().Str("cid", c.bundle.CID).RawJSON("result", respBody).Msg("telemetry stats submitted")
+	c.consecutive5xx = 0
+
+	return nil
+}
+
+// put sends a submission request with body (the, possibly compressed, payload)
+// to subURL. bodySize returns the number of body bytes sent, it is called once
+// the request completes. Returns the broker response, or a submitError.
+func (c *Check) put(client *http.Client, subURL string, body io.Reader, encoding string, bodySize func() int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), "PUT", subURL, body)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("User-Agent", release.NAME+"/"+release.VERSION)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Connection", "close")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
 
+	submitStart := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		client.CloseIdleConnections()
-		return err
+		c.recordSubmit(bodySize(), time.Since(submitStart), 0, true)
+		return nil, &submitError{err: err}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close() // nolint: errcheck
+	c.recordSubmit(bodySize(), time.Since(submitStart), resp.StatusCode, err != nil || resp.StatusCode != http.StatusOK)
 	if err != nil {
-		client.CloseIdleConnections()
-		return err
+		return nil, &submitError{err: err}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		if pr, isPipeReader := metricSrc.(*io.PipeReader); isPipeReader {
-			if err = pr.Close(); err != nil {
-				c.logger.Warn().Err(err).Msg("closing pipe reader")
-			}
-		}
-		c.logger.Error().Err(err).Str("url", subURL).Str("status", resp.Status).RawJSON("response", body).Msg("submitting telemetry")
-		client.CloseIdleConnections()
-		return errors.Wrap(err, "submitting metrics")
+		c.logger.Error().Str("url", subURL).Str("status", resp.Status).RawJSON("response", respBody).Msg("submitting telemetry")
+		return nil, &submitError{status: resp.Status, statusCode: resp.StatusCode}
 	}
 
-	c.logger.Debug().Str("cid", c.bundle.CID).RawJSON("result", body).Msg("telemetry stats submitted")
-
-	client.CloseIdleConnections()
-
-	return nil
+	return respBody, nil
 }
 
 // WriteMetricSample to queue for submission.
 func (c *Check) WriteMetricSample(metricDest io.Writer, metricName, metricType string, value interface{}, timestamp *time.Time) error {
+	return c.writeMetricSample(metricDest, metricName, metricType, value, timestamp, true)
+}
+
+// writeMetricSample writes a metric sample to metricDest. If track is false
+// the sample is not counted in the check's stats (e.g. the stats themselves).
+func (c *Check) writeMetricSample(metricDest io.Writer, metricName, metricType string, value interface{}, timestamp *time.Time, track bool) error {
 	if metricDest == nil {
 		return errors.New("invalid metric destination (nil)")
 	}
@@ -144,6 +265,9 @@ func (c *Check) WriteMetricSample(metricDest io.Writer, metricName, metricType s
 			Int("encoded_len", len(metricName)).
 			Int("max_len", MaxMetricNameLen).
 			Msg("max metric name length exceeded, discarding")
+		if track {
+			c.recordSample(true)
+		}
 		return nil
 	}
 
@@ -185,5 +309,8 @@ func (c *Check) WriteMetricSample(metricDest io.Writer, metricName, metricType s
 	if err != nil {
 		return err
 	}
+	if track {
+		c.recordSample(false)
+	}
 	return nil
 }
@@ -0,0 +1,202 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+// bundleChange is a check bundle setting which differs from the configuration.
+type bundleChange struct {
+	Field   string
+	Current interface{}
+	Desired interface{}
+}
+
+// checkNotes returns the notes the agent sets on check bundles.
+func checkNotes() string {
+	return fmt.Sprintf("%s-%s", release.NAME, release.VERSION)
+}
+
+// reconcileNotes returns notes with the agent's line (see checkNotes, e.g. from
+// an older version) replaced, or appended if there is none. Other lines (e.g.
+// added in the UI) are kept.
+func reconcileNotes(notes string) string {
+	agentNotes := checkNotes()
+	if notes == "" {
+		return agentNotes
+	}
+
+	lines := strings.Split(notes, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, release.NAME+"-") {
+			lines[i] = agentNotes
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	return strings.TrimRight(notes, "\n") + "\n" + agentNotes
+}
+
+// checkTags returns the check bundle tags from a comma separated list.
+func checkTags(tags string) []string {
+	var list []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// reconcileCheckBundle brings an existing check bundle in line with the
+// configuration (display name, tags, notes, metric filters). Depending on the
+// reconcile mode, the differences are updated (update, the default), only
+// logged (log), or ignored (off). The check is created or found again
+// whenever an instance is (re)started, so configuration changes (e.g. tags)
+// take effect on startup and reload. Failing to update is not fatal, the
+// bundle is used as is.
+func (c *Check) reconcileCheckBundle(bundle *apiclient.CheckBundle) *apiclient.CheckBundle {
+	if c.config.Reconcile == ReconcileOff {
+		return bundle
+	}
+
+	desired, changes := c.desiredCheckBundle(*bundle)
+	if len(changes) == 0 {
+		return bundle
+	}
+
+	for _, change := range changes {
+		c.logger.Info().
+			Str("check_bundle", bundle.CID).
+			Str("field", change.Field).
+			Interface("current", change.Current).
+			Interface("desired", change.Desired).
+			Bool("update", c.config.Reconcile != ReconcileLog).
+			Msg("check bundle differs from config")
+	}
+
+	if c.config.Reconcile == ReconcileLog {
+		return bundle
+	}
+
+	updated, err := c.apih.UpdateCheckBundle(&desired)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("check_bundle", bundle.CID).Msg("updating check bundle, using as is")
+		return bundle
+	}
+
+	c.logger.Info().Str("check_bundle", bundle.CID).Int("changes", len(changes)).Msg("check bundle updated")
+	return updated
+}
+
+// desiredCheckBundle returns a copy of bundle with the settings managed by the
+// agent set from the configuration, and the settings changed. Existing tags in
+// a category the configuration sets (e.g. env) are replaced, tags in other
+// categories (e.g. added in the UI) are kept. Only the agent's line of the
+// notes is managed (see reconcileNotes), and metric filters only when
+// configured.
+func (c *Check) desiredCheckBundle(bundle apiclient.CheckBundle) (apiclient.CheckBundle, []bundleChange) {
+	var changes []bundleChange
+
+	if c.config.DisplayName != "" && bundle.DisplayName != c.config.DisplayName {
+		changes = append(changes, bundleChange{Field: "display_name", Current: bundle.DisplayName, Desired: c.config.DisplayName})
+		bundle.DisplayName = c.config.DisplayName
+	}
+
+	if tags := reconcileTags(bundle.Tags, checkTags(c.config.Tags)); !sameTags(bundle.Tags, tags) {
+		changes = append(changes, bundleChange{Field: "tags", Current: bundle.Tags, Desired: tags})
+		bundle.Tags = tags
+	}
+
+	current := ""
+	if bundle.Notes != nil {
+		current = *bundle.Notes
+	}
+	if notes := reconcileNotes(current); notes != current {
+		changes = append(changes, bundleChange{Field: "notes", Current: current, Desired: notes})
+		bundle.Notes = &notes
+	}
+
+	if len(c.config.MetricFilters) > 0 {
+		if filters := c.metricFilters(); !sameMetricFilters(bundle.MetricFilters, filters) {
+			changes = append(changes, bundleChange{Field: "metric_filters", Current: bundle.MetricFilters, Desired: filters})
+			bundle.MetricFilters = filters
+		}
+	}
+
+	return bundle, changes
+}
+
+// tagCategory returns the category of a tag (e.g. env for env:prod), tags
+// without a value are their own category.
+func tagCategory(tag string) string {
+	if i := strings.Index(tag, ":"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(tag)
+}
+
+// reconcileTags returns current with the tags in a category of one of desired
+// replaced by desired.
+func reconcileTags(current, desired []string) []string {
+	categories := make(map[string]bool, len(desired))
+	for _, tag := range desired {
+		categories[tagCategory(tag)] = true
+	}
+	tags := make([]string, 0, len(current)+len(desired))
+	for _, tag := range current {
+		if !categories[tagCategory(tag)] {
+			tags = append(tags, tag)
+		}
+	}
+	return append(tags, desired...)
+}
+
+// sameTags returns true if a and b contain the same tags, ignoring order and
+// case (the api lowercases tags).
+func sameTags(a, b []string) bool {
+	set := func(tags []string) map[string]bool {
+		m := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			m[strings.ToLower(tag)] = true
+		}
+		return m
+	}
+	return reflect.DeepEqual(set(a), set(b))
+}
+
+// sameMetricFilters returns true if the filters are the same, ignoring
+// comments which the api may omit.
+func sameMetricFilters(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(filterRule(a[i]), filterRule(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterRule returns a check bundle metric filter without its comment, the
+// type and regex, plus the tag query for tag filters.
+func filterRule(f []string) []string {
+	switch {
+	case len(f) >= 4 && f[2] == "tags":
+		return f[:4]
+	case len(f) >= 2:
+		return f[:2]
+	default:
+		return f
+	}
+}
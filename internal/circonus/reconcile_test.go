@@ -0,0 +1,103 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+func TestDesiredCheckBundle(t *testing.T) {
+	t.Log("Testing desiredCheckBundle")
+
+	c := &Check{config: &Config{
+		DisplayName: "aws prod us-east-1 /circonus-cloud-agent",
+		Tags:        "circonus-cloud-agent:aws,aws_region:us-east-1,env:prod",
+	}}
+	notes := checkNotes()
+
+	t.Log("\tin sync")
+	{
+		bundle := apiclient.CheckBundle{
+			DisplayName:   c.config.DisplayName,
+			Tags:          []string{"circonus-cloud-agent:aws", "aws_region:us-east-1", "env:prod", "team:ops"},
+			Notes:         &notes,
+			MetricFilters: [][]string{{"deny", "^$"}, {"allow", "^.+$"}},
+		}
+		if _, changes := c.desiredCheckBundle(bundle); len(changes) != 0 {
+			t.Fatalf("expected no changes, got %+v", changes)
+		}
+	}
+
+	t.Log("\tchanged")
+	{
+		userNotes := "owned by ops, see runbook\ncirconus-cloud-agent-v0.1.0"
+		bundle := apiclient.CheckBundle{
+			DisplayName:   "old name",
+			Tags:          []string{"circonus-cloud-agent:aws", "aws_region:us-east-1", "env:staging", "team:ops"},
+			Notes:         &userNotes,
+			MetricFilters: [][]string{{"allow", "^cpu", "ui"}, {"deny", "^.+$", "ui"}},
+		}
+		desired, changes := c.desiredCheckBundle(bundle)
+		if len(changes) != 3 {
+			t.Fatalf("expected 3 changes, got %+v", changes)
+		}
+		if desired.DisplayName != c.config.DisplayName {
+			t.Fatalf("expected display name (%s), got (%s)", c.config.DisplayName, desired.DisplayName)
+		}
+		if !sameTags(desired.Tags, []string{"team:ops", "circonus-cloud-agent:aws", "aws_region:us-east-1", "env:prod"}) {
+			t.Fatalf("expected env replaced and team kept, got %v", desired.Tags)
+		}
+		if expect := "owned by ops, see runbook\n" + notes; *desired.Notes != expect {
+			t.Fatalf("expected notes (%s), got (%s)", expect, *desired.Notes)
+		}
+		if len(desired.MetricFilters) != 2 {
+			t.Fatalf("expected ui metric filters kept, got %v", desired.MetricFilters)
+		}
+	}
+
+	t.Log("\tmetric filters configured")
+	{
+		fc := &Check{config: &Config{
+			DisplayName:   c.config.DisplayName,
+			Tags:          c.config.Tags,
+			MetricFilters: []MetricFilter{{Type: MetricFilterAllow, Filter: "^cpu"}},
+		}}
+		bundle := apiclient.CheckBundle{
+			DisplayName:   c.config.DisplayName,
+			Tags:          []string{"circonus-cloud-agent:aws", "aws_region:us-east-1", "env:prod"},
+			Notes:         &notes,
+			MetricFilters: [][]string{{"allow", "^.+$", ""}},
+		}
+		desired, changes := fc.desiredCheckBundle(bundle)
+		if len(changes) != 1 || changes[0].Field != "metric_filters" {
+			t.Fatalf("expected metric_filters change, got %+v", changes)
+		}
+		if !sameMetricFilters(desired.MetricFilters, fc.metricFilters()) {
+			t.Fatalf("expected metric filters %v, got %v", fc.metricFilters(), desired.MetricFilters)
+		}
+	}
+}
+
+func TestReconcileNotes(t *testing.T) {
+	t.Log("Testing reconcileNotes")
+
+	notes := checkNotes()
+	tests := map[string]string{
+		"":                            notes,
+		notes:                         notes,
+		"circonus-cloud-agent-v0.1.0": notes,
+		"see runbook":                 "see runbook\n" + notes,
+		"see runbook\n":               "see runbook\n" + notes,
+		"circonus-cloud-agent-v0.1.0\nsee runbook": notes + "\nsee runbook",
+	}
+	for in, expect := range tests {
+		if got := reconcileNotes(in); got != expect {
+			t.Fatalf("expected (%s) for (%s), got (%s)", expect, in, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"crypto/x509"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// refreshMinInterval limits how often a failed submission can trigger a check refresh.
+	refreshMinInterval = 5 * time.Minute
+
+	// refreshAfter5xx is the number of consecutive broker errors (5xx) which trigger a check refresh.
+	refreshAfter5xx = 3
+)
+
+// refreshCause returns the reason a failed submission indicates the check
+// may have been moved to a different broker, or an empty string if it does
+// not. The caller must hold the check lock.
+func (c *Check) refreshCause(err error) string {
+	var serr *submitError
+	if errors.As(err, &serr) && serr.statusCode >= http.StatusInternalServerError {
+		c.consecutive5xx++
+	} else {
+		c.consecutive5xx = 0
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case isHostnameError(err):
+		return "tls certificate name mismatch"
+	case serr != nil && serr.statusCode == http.StatusNotFound:
+		return "submission url not found"
+	case c.consecutive5xx >= refreshAfter5xx:
+		return "repeated broker errors"
+	default:
+		return ""
+	}
+}
+
+// refreshOnError refreshes the check bundle and broker (e.g. the check was
+// moved to a different broker) if a failed submission indicates it may be
+// needed, at most once every refreshMinInterval. Returns true if the check
+// was refreshed and the submission should be retried. The caller must hold
+// the check lock.
+func (c *Check) refreshOnError(err error) bool {
+	cause := c.refreshCause(err)
+	if cause == "" {
+		return false
+	}
+
+	if !c.lastRefresh.IsZero() && time.Since(c.lastRefresh) < refreshMinInterval {
+		c.logger.Debug().Str("cause", cause).Time("last_refresh", c.lastRefresh).Msg("check refresh rate limited")
+		return false
+	}
+	c.lastRefresh = time.Now()
+
+	prevURL := c.bundle.Config["submission_url"]
+	c.logger.Warn().Err(err).Str("cause", cause).Msg("submission failed, refreshing check")
+	if rerr := c.refresh(); rerr != nil {
+		c.logger.Error().Err(rerr).Msg("refreshing check")
+		return false
+	}
+	c.consecutive5xx = 0
+
+	c.logger.Info().
+		Str("prev_submission_url", prevURL).
+		Str("submission_url", c.bundle.Config["submission_url"]).
+		Msg("check refreshed, resubmitting")
+
+	return true
+}
+
+func isHostnameError(err error) bool {
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return true
+	}
+	var hostnameErrPtr *x509.HostnameError
+	return errors.As(err, &hostnameErrPtr)
+}
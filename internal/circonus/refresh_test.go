@@ -0,0 +1,103 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func TestRefreshCause(t *testing.T) {
+	t.Log("Testing refreshCause")
+
+	c := &Check{
+		logger: zerolog.Nop(),
+		bundle: &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": "https://127.0.0.1/module/httptrap/x/y"}},
+	}
+
+	t.Log("connection refused")
+	{
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		addr := l.Addr().String()
+		l.Close()
+		_, err = http.Get("http://" + addr) //nolint:noctx
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if cause := c.refreshCause(&submitError{err: err}); cause != "connection refused" {
+			t.Fatalf("expected connection refused, got (%s)", cause)
+		}
+	}
+
+	t.Log("tls name mismatch")
+	{
+		err := &tls.CertificateVerificationError{Err: x509.HostnameError{Host: "broker.example.com"}}
+		if cause := c.refreshCause(&submitError{err: errors.Wrap(err, "put")}); cause == "" {
+			t.Fatal("expected cause")
+		}
+	}
+
+	t.Log("404")
+	{
+		if cause := c.refreshCause(&submitError{statusCode: http.StatusNotFound}); cause == "" {
+			t.Fatal("expected cause")
+		}
+	}
+
+	t.Log("400")
+	{
+		if cause := c.refreshCause(&submitError{statusCode: http.StatusBadRequest}); cause != "" {
+			t.Fatalf("expected no cause, got (%s)", cause)
+		}
+	}
+
+	t.Log("repeated 5xx")
+	{
+		for i := 1; i <= refreshAfter5xx; i++ {
+			cause := c.refreshCause(&submitError{statusCode: http.StatusBadGateway})
+			if i < refreshAfter5xx && cause != "" {
+				t.Fatalf("expected no cause after %d, got (%s)", i, cause)
+			}
+			if i == refreshAfter5xx && cause == "" {
+				t.Fatalf("expected cause after %d", i)
+			}
+		}
+	}
+
+	t.Log("refresh fails, bundle retained")
+	{
+		if c.refreshOnError(&submitError{statusCode: http.StatusNotFound}) {
+			t.Fatal("expected refresh to fail (no api client)")
+		}
+		if c.lastRefresh.IsZero() {
+			t.Fatal("expected last refresh to be set")
+		}
+		if c.bundle == nil {
+			t.Fatal("expected bundle to be retained")
+		}
+	}
+
+	t.Log("rate limited")
+	{
+		last := c.lastRefresh
+		if c.refreshOnError(&submitError{statusCode: http.StatusNotFound}) {
+			t.Fatal("expected no refresh")
+		}
+		if !c.lastRefresh.Equal(last) {
+			t.Fatal("expected refresh to be rate limited")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+// Sink is a destination for the metric samples produced by collectors.
+// Check is the Sink which submits samples to Circonus, other sinks (see
+// package sinks) route the same samples to additional systems.
+type Sink interface {
+	// NewBatch returns an empty batch of samples for the sink.
+	NewBatch() Batch
+}
+
+// Batch is a set of metric samples delivered to a sink together (e.g. all
+// of the samples for a single EC2 instance).
+type Batch interface {
+	// WriteMetricSample adds a sample to the batch. metricName may include
+	// stream tags (see MetricNameWithStreamTags). If timestamp is nil the
+	// sink's receipt time is used.
+	WriteMetricSample(metricName, metricType string, value interface{}, timestamp *time.Time) error
+	// Len returns the size of the batch, zero if no samples have been written.
+	Len() int
+	// Submit delivers the samples written to the sink, the batch is reset
+	// and can be reused.
+	Submit() error
+	// Discard the samples written, the batch is reset and can be reused.
+	// Discarding an empty batch is a no-op, so it can be deferred to cover
+	// early returns.
+	Discard()
+}
+
+// CounterWriter is implemented by batches for sinks which distinguish
+// counters from gauges (e.g. OTLP sums). Circonus does not, counters are
+// numeric samples.
+type CounterWriter interface {
+	WriteCounterSample(metricName string, value float64, timestamp *time.Time) error
+}
+
+// WriteCounterSample adds a counter sample to b, as a counter if the sink
+// distinguishes counters (see CounterWriter), otherwise as a numeric sample.
+func WriteCounterSample(b Batch, metricName string, value float64, timestamp *time.Time) error {
+	if cw, ok := b.(CounterWriter); ok {
+		return cw.WriteCounterSample(metricName, value, timestamp)
+	}
+	return b.WriteMetricSample(metricName, MetricTypeFloat64, value, timestamp)
+}
+
+// NewBatch returns a Submission (see NewSubmission) for the check.
+func (c *Check) NewBatch() Batch {
+	return c.NewSubmission()
+}
+
+// WriteMetricSample adds a sample to the submission (see Check.WriteMetricSample).
+func (s *Submission) WriteMetricSample(metricName, metricType string, value interface{}, timestamp *time.Time) error {
+	return s.check.WriteMetricSample(s, metricName, metricType, value, timestamp)
+}
+
+// ParseMetricName splits a metric name into the base name and the stream
+// tags encoded by MetricNameWithStreamTags (base64 encoded tag categories
+// and values are decoded). Used by sinks for systems which represent tags
+// (labels, attributes, etc.) separately from the metric name.
+func ParseMetricName(metricName string) (string, Tags) {
+	idx := strings.Index(metricName, "|ST[")
+	if idx == -1 || !strings.HasSuffix(metricName, "]") {
+		return metricName, nil
+	}
+
+	name := metricName[:idx]
+	tagList := metricName[idx+4 : len(metricName)-1]
+	if tagList == "" {
+		return name, nil
+	}
+
+	var tags Tags
+	for _, tag := range strings.Split(tagList, ",") {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		tags = append(tags, Tag{Category: decodeStreamTag(parts[0]), Value: decodeStreamTag(parts[1])})
+	}
+
+	return name, tags
+}
+
+// decodeStreamTag decodes a stream tag category or value, if it is base64 encoded (b"...").
+func decodeStreamTag(s string) string {
+	if !strings.HasPrefix(s, `b"`) || !strings.HasSuffix(s, `"`) || len(s) < 3 {
+		return s
+	}
+	v, err := base64.StdEncoding.DecodeString(s[2 : len(s)-1])
+	if err != nil {
+		return s
+	}
+	return string(v)
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseMetricName(t *testing.T) {
+	t.Log("Testing ParseMetricName")
+
+	c := &Check{logger: zerolog.Nop()}
+
+	t.Log("no tags")
+	{
+		name, tags := ParseMetricName("foo`bar")
+		if name != "foo`bar" || len(tags) != 0 {
+			t.Fatalf("unexpected result %s %v", name, tags)
+		}
+	}
+
+	t.Log("stream tags")
+	{
+		mn := c.MetricNameWithStreamTags("CPUUtilization`Average", Tags{{Category: "service", Value: "AWS/EC2"}, {Category: "region", Value: "us-east-1"}})
+		name, tags := ParseMetricName(mn)
+		if name != "CPUUtilization`Average" {
+			t.Fatalf("unexpected name %s", name)
+		}
+		if len(tags) != 2 {
+			t.Fatalf("expected 2 tags, got %v", tags)
+		}
+		found := map[string]string{}
+		for _, tag := range tags {
+			found[tag.Category] = tag.Value
+		}
+		if found["service"] != "aws/ec2" || found["region"] != "us-east-1" {
+			t.Fatalf("unexpected tags %v", tags)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// spool holds metric payloads which could not be submitted, so they can be
+// replayed, in order, once the broker is reachable again. Payloads contain
+// sample timestamps (_ts) so late delivery is still meaningful. NOTE: not
+// safe for concurrent use, all access is made holding the check lock.
+type spool struct {
+	logger  zerolog.Logger
+	dir     string
+	maxSize int64
+	seq     uint64
+}
+
+const spoolFileExt = ".json"
+
+var spoolDirRx = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// newSpool creates a spool in a sub-directory of baseDir for the check id.
+func newSpool(baseDir, checkID string, maxSize int64, logger zerolog.Logger) (*spool, error) {
+	if baseDir == "" {
+		return nil, errors.New("invalid spool dir (empty)")
+	}
+	if checkID == "" {
+		return nil, errors.New("invalid check id (empty)")
+	}
+	if maxSize <= 0 {
+		return nil, errors.Errorf("invalid spool max size (%d)", maxSize)
+	}
+
+	dir := filepath.Join(baseDir, spoolDirRx.ReplaceAllString(checkID, "_"))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(err, "creating spool dir")
+	}
+
+	return &spool{
+		dir:     dir,
+		maxSize: maxSize,
+		logger:  logger.With().Str("spool_dir", dir).Logger(),
+	}, nil
+}
+
+// add writes a payload to the spool. If the spool would exceed its maximum
+// size, the oldest payloads are removed to make room.
+func (s *spool) add(payload []byte) error {
+	size := int64(len(payload))
+	if size > s.maxSize {
+		return errors.Errorf("payload size (%d) exceeds spool max size (%d)", size, s.maxSize)
+	}
+
+	w, err := s.create()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		w.discard()
+		return errors.Wrap(err, "writing spooled payload")
+	}
+
+	return w.commit()
+}
+
+// spoolWriter writes a payload to a temporary (hidden) file in the spool,
+// which is added to the spool by commit or removed by discard. Used to
+// capture a streamed submission, so it can be spooled if the submission fails.
+type spoolWriter struct {
+	s    *spool
+	f    *os.File
+	name string
+	size int64
+}
+
+// create returns a writer for a new payload. The name reserved for the
+// payload when it is created determines its place in the replay order.
+func (s *spool) create() (*spoolWriter, error) {
+	// names sort in the order payloads were spooled
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d", time.Now().UnixNano(), s.seq%1000000)
+	f, err := os.OpenFile(filepath.Join(s.dir, "."+name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating spooled payload")
+	}
+	return &spoolWriter{s: s, f: f, name: name}, nil
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// commit adds the payload to the spool. If the spool would exceed its
+// maximum size, the oldest payloads are removed to make room.
+func (w *spoolWriter) commit() error {
+	tmp := w.f.Name()
+	if err := w.f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrap(err, "writing spooled payload")
+	}
+
+	if w.size > w.s.maxSize {
+		_ = os.Remove(tmp)
+		return errors.Errorf("payload size (%d) exceeds spool max size (%d)", w.size, w.s.maxSize)
+	}
+
+	files, total, err := w.s.files()
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	for len(files) > 0 && total+w.size > w.s.maxSize {
+		oldest := files[0]
+		files = files[1:]
+		if err := os.Remove(oldest.path); err != nil {
+			_ = os.Remove(tmp)
+			return errors.Wrap(err, "removing spooled payload")
+		}
+		total -= oldest.size
+		w.s.logger.Warn().Str("file", filepath.Base(oldest.path)).Int64("size", oldest.size).Msg("spool full, dropped oldest payload")
+	}
+
+	if err := os.Rename(tmp, filepath.Join(w.s.dir, w.name+spoolFileExt)); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrap(err, "writing spooled payload")
+	}
+
+	return nil
+}
+
+// discard removes the payload, it is not added to the spool.
+func (w *spoolWriter) discard() {
+	tmp := w.f.Name()
+	_ = w.f.Close()
+	_ = os.Remove(tmp)
+}
+
+// replay submits spooled payloads, oldest first, removing each once submitted.
+// Replay stops at the first failure, leaving the remaining payloads (including
+// the failed one) in the spool. Returns the number of payloads submitted.
+func (s *spool) replay(submit func(payload []byte) error) (int, error) {
+	files, _, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, f := range files {
+		payload, err := os.ReadFile(f.path)
+		if err != nil {
+			return sent, errors.Wrap(err, "reading spooled payload")
+		}
+		if err := submit(payload); err != nil {
+			return sent, err
+		}
+		if err := os.Remove(f.path); err != nil {
+			return sent, errors.Wrap(err, "removing spooled payload")
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// files returns the spooled payload files, oldest first, and their total size.
+func (s *spool) files() ([]spoolFile, int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "reading spool dir")
+	}
+
+	var total int64
+	files := make([]spoolFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || filepath.Ext(entry.Name()) != spoolFileExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since the directory was read
+		}
+		files = append(files, spoolFile{path: filepath.Join(s.dir, entry.Name()), size: info.Size()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	return files, total, nil
+}
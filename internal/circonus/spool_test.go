@@ -0,0 +1,227 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func TestSpool(t *testing.T) {
+	t.Log("Testing spool")
+
+	t.Log("invalid")
+	{
+		if _, err := newSpool("", "id", 10, zerolog.Nop()); err == nil {
+			t.Fatal("expected error")
+		}
+		if _, err := newSpool(t.TempDir(), "id", 0, zerolog.Nop()); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	s, err := newSpool(t.TempDir(), "aws_test_us-east-1", 10, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	t.Log("payload too large")
+	{
+		if err := s.add([]byte("12345678901")); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("max size, oldest dropped")
+	{
+		for _, p := range []string{"aaaa", "bbbb", "cccc"} {
+			if err := s.add([]byte(p)); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+		files, total, err := s.files()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(files) != 2 || total != 8 {
+			t.Fatalf("expected 2 files (8 bytes), got %d (%d bytes)", len(files), total)
+		}
+	}
+
+	t.Log("replay stops on failure")
+	{
+		sent, err := s.replay(func(payload []byte) error { return errors.New("fail") })
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if sent != 0 {
+			t.Fatalf("expected 0 sent, got %d", sent)
+		}
+	}
+
+	t.Log("replay in order")
+	{
+		var got []string
+		sent, err := s.replay(func(payload []byte) error {
+			got = append(got, string(payload))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if sent != 2 || got[0] != "bbbb" || got[1] != "cccc" {
+			t.Fatalf("expected [bbbb cccc], got %v", got)
+		}
+		if files, _, _ := s.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d files", len(files))
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Log("Testing retryDelay")
+
+	for attempt, max := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 10: 30 * time.Second, 100: 30 * time.Second} {
+		d := retryDelay(attempt)
+		if d < max/2 || d > max {
+			t.Fatalf("attempt %d, expected %s-%s, got %s", attempt, max/2, max, d)
+		}
+	}
+}
+
+func TestSubmitMetrics(t *testing.T) {
+	t.Log("Testing SubmitMetrics")
+
+	submitRetryMinDelay = time.Millisecond
+	submitRetryMaxDelay = 2 * time.Millisecond
+
+	var mu sync.Mutex
+	var failures int
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	spool, err := newSpool(t.TempDir(), "test", 1024, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	c := &Check{
+		config:       &Config{SubmitRetries: 2},
+		bundle:       &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": ts.URL}},
+		spool:        spool,
+		logger:       zerolog.Nop(),
+		metricTypeRx: regexp.MustCompile("^[iIlLns]$"),
+	}
+
+	t.Log("retry succeeds")
+	{
+		failures = 2
+		if err := c.SubmitMetrics(bytes.NewBufferString("one")); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("retries exhausted, spooled")
+	{
+		failures = 3
+		if err := c.SubmitMetrics(bytes.NewBufferString("two")); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if files, _, _ := spool.files(); len(files) != 1 {
+			t.Fatalf("expected 1 spooled payload, got %d", len(files))
+		}
+	}
+
+	t.Log("spool replayed after success")
+	{
+		if err := c.SubmitMetrics(bytes.NewBufferString("three")); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		expected := []string{"one", "three", "two"}
+		if len(received) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, received)
+		}
+		for i := range expected {
+			if received[i] != expected[i] {
+				t.Fatalf("expected %v, got %v", expected, received)
+			}
+		}
+	}
+}
+
+func TestSubmitRetryUnlocked(t *testing.T) {
+	t.Log("Testing submitWithRetry, lock released while waiting to retry")
+
+	submitRetryMinDelay = 500 * time.Millisecond
+	submitRetryMaxDelay = time.Second
+	defer func() {
+		submitRetryMinDelay = time.Millisecond
+		submitRetryMaxDelay = 2 * time.Millisecond
+	}()
+
+	failed := make(chan struct{})
+	var once sync.Once
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first := false
+		once.Do(func() { first = true })
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			close(failed)
+			return
+		}
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	c := &Check{
+		config: &Config{SubmitRetries: 1},
+		bundle: &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": ts.URL}},
+		logger: zerolog.Nop(),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.SubmitMetrics(bytes.NewBufferString("one"))
+	}()
+
+	<-failed
+	locked := false
+	for i := 0; i < 20 && !locked; i++ {
+		time.Sleep(10 * time.Millisecond)
+		locked = c.TryLock()
+	}
+	if !locked {
+		t.Fatal("expected check lock released while waiting to retry")
+	}
+	c.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/telemetry"
+	"github.com/pkg/errors"
+)
+
+// Agent internals exposed in prometheus format (see telemetry.Handler), labeled
+// with the service (aws, azure, gcp) and instance (the check id, e.g. aws_prod_us-east-1).
+var (
+	promPrefix = strings.ReplaceAll(release.NAME, "-", "_") + "_"
+
+	promCollectionDuration = telemetry.NewHistogramVec(promPrefix+"collection_duration_seconds", "Duration of instance collections.", nil, "service", "instance")
+	promCollectorDuration  = telemetry.NewHistogramVec(promPrefix+"collector_duration_seconds", "Duration of individual collectors within an instance collection.", nil, "service", "instance", "collector")
+	promAPICalls           = telemetry.NewCounterVec(promPrefix+"api_calls_total", "Cloud service API calls (CloudWatch, Azure Monitor, GCP Monitoring, etc.).", "service", "instance", "collector")
+	promAPIErrors          = telemetry.NewCounterVec(promPrefix+"api_errors_total", "Cloud service API calls which failed, by error code.", "service", "instance", "collector", "code")
+	promSamplesWritten     = telemetry.NewCounterVec(promPrefix+"samples_written_total", "Metric samples written for submission.", "service", "instance")
+	promSamplesDiscarded   = telemetry.NewCounterVec(promPrefix+"samples_discarded_total", "Metric samples discarded, name exceeded max metric name length.", "service", "instance")
+	promSubmitDuration     = telemetry.NewHistogramVec(promPrefix+"submit_duration_seconds", "Latency of metric submissions to the broker.", nil, "service", "instance")
+	promSubmitFailures     = telemetry.NewCounterVec(promPrefix+"submit_failures_total", "Metric submissions to the broker which failed.", "service", "instance")
+	promSubmitBytes        = telemetry.NewCounterVec(promPrefix+"submit_bytes_total", "Metric payload bytes submitted to the broker.", "service", "instance")
+)
+
+// deleteTelemetry removes the series for the check (service and instance)
+// from the agent internals exposed in prometheus format.
+func (c *Check) deleteTelemetry() {
+	for _, v := range []interface{ DeletePrefix(...string) }{
+		promCollectionDuration,
+		promCollectorDuration,
+		promAPICalls,
+		promAPIErrors,
+		promSamplesWritten,
+		promSamplesDiscarded,
+		promSubmitDuration,
+		promSubmitFailures,
+		promSubmitBytes,
+	} {
+		v.DeletePrefix(c.svcID, c.config.ID)
+	}
+}
+
+// Stats defines the self-telemetry counters maintained by a check. The
+// counters are cumulative, use Sub to obtain the change over a period
+// (e.g. a single collector or an entire collection run).
+type Stats struct {
+	APIErrors        map[string]uint64 // cloud api errors, by error code
+	SubmitStatus     map[int]uint64    // metric submissions, by http status code (0 = no response)
+	APICalls         uint64            // cloud api calls made
+	SamplesWritten   uint64            // metric samples written for submission
+	SamplesDiscarded uint64            // metric samples discarded, name (w/stream tags) exceeded MaxMetricNameLen
+	Submits          uint64            // metric submissions attempted
+	SubmitErrors     uint64            // metric submissions which failed
+	BytesSubmitted   uint64            // metric payload bytes submitted
+	SubmitLatency    time.Duration     // total time spent submitting metrics
+}
+
+// Sub returns the change in counters since prev.
+func (s Stats) Sub(prev Stats) Stats {
+	d := Stats{
+		APIErrors:        make(map[string]uint64),
+		SubmitStatus:     make(map[int]uint64),
+		APICalls:         s.APICalls - prev.APICalls,
+		SamplesWritten:   s.SamplesWritten - prev.SamplesWritten,
+		SamplesDiscarded: s.SamplesDiscarded - prev.SamplesDiscarded,
+		Submits:          s.Submits - prev.Submits,
+		SubmitErrors:     s.SubmitErrors - prev.SubmitErrors,
+		BytesSubmitted:   s.BytesSubmitted - prev.BytesSubmitted,
+		SubmitLatency:    s.SubmitLatency - prev.SubmitLatency,
+	}
+	for code, n := range s.APIErrors {
+		if v := n - prev.APIErrors[code]; v > 0 {
+			d.APIErrors[code] = v
+		}
+	}
+	for code, n := range s.SubmitStatus {
+		if v := n - prev.SubmitStatus[code]; v > 0 {
+			d.SubmitStatus[code] = v
+		}
+	}
+	return d
+}
+
+// Stats returns a copy of the check's current self-telemetry counters.
+func (c *Check) Stats() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	s := c.stats
+	s.APIErrors = make(map[string]uint64, len(c.stats.APIErrors))
+	for k, v := range c.stats.APIErrors {
+		s.APIErrors[k] = v
+	}
+	s.SubmitStatus = make(map[int]uint64, len(c.stats.SubmitStatus))
+	for k, v := range c.stats.SubmitStatus {
+		s.SubmitStatus[k] = v
+	}
+	return s
+}
+
+// RecordAPICall tracks a call made to a cloud service provider api by a
+// collector (empty for services which do not use collectors). errCode
+// should be empty if the call succeeded, otherwise an identifier for the
+// error (e.g. aws error code, http status code).
+func (c *Check) RecordAPICall(collector, errCode string) {
+	promAPICalls.Inc(c.svcID, c.config.ID, collector)
+	if errCode != "" {
+		promAPIErrors.Inc(c.svcID, c.config.ID, collector, errCode)
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats.APICalls++
+	if errCode != "" {
+		if c.stats.APIErrors == nil {
+			c.stats.APIErrors = make(map[string]uint64)
+		}
+		c.stats.APIErrors[errCode]++
+	}
+}
+
+// RecordCollection tracks the duration of a collector, or of the
+// entire instance collection when collector is empty.
+func (c *Check) RecordCollection(collector string, duration time.Duration) {
+	if collector == "" {
+		promCollectionDuration.Observe(duration.Seconds(), c.svcID, c.config.ID)
+		return
+	}
+	promCollectorDuration.Observe(duration.Seconds(), c.svcID, c.config.ID, collector)
+}
+
+// recordSample tracks metric samples written or discarded.
+func (c *Check) recordSample(discarded bool) {
+	if discarded {
+		promSamplesDiscarded.Inc(c.svcID, c.config.ID)
+	} else {
+		promSamplesWritten.Inc(c.svcID, c.config.ID)
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if discarded {
+		c.stats.SamplesDiscarded++
+		return
+	}
+	c.stats.SamplesWritten++
+}
+
+// recordSubmit tracks a metric submission attempt. statusCode is 0 if
+// no response was received from the broker.
+func (c *Check) recordSubmit(numBytes int, latency time.Duration, statusCode int, failed bool) {
+	promSubmitDuration.Observe(latency.Seconds(), c.svcID, c.config.ID)
+	promSubmitBytes.Add(float64(numBytes), c.svcID, c.config.ID)
+	if failed {
+		promSubmitFailures.Inc(c.svcID, c.config.ID)
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats.Submits++
+	c.stats.BytesSubmitted += uint64(numBytes)
+	c.stats.SubmitLatency += latency
+	if failed {
+		c.stats.SubmitErrors++
+	}
+	if c.stats.SubmitStatus == nil {
+		c.stats.SubmitStatus = make(map[int]uint64)
+	}
+	c.stats.SubmitStatus[statusCode]++
+}
+
+// WriteStats writes the self-telemetry metrics for a period (e.g. the
+// result of Sub) to metricDest. duration is the collection duration for the
+// period, tags are added to every metric (e.g. collector:AWS/EC2). The stats
+// samples are not counted in the check's stats.
+func (c *Check) WriteStats(metricDest io.Writer, duration time.Duration, stats Stats, tags Tags) error {
+	if metricDest == nil {
+		return errors.New("invalid metric destination (nil)")
+	}
+
+	name := func(metric string, extra ...Tag) string {
+		var mt Tags
+		mt = append(mt, tags...)
+		mt = append(mt, extra...)
+		return c.MetricNameWithStreamTags(c.statsMetricPrefix+metric, mt)
+	}
+
+	type sample struct {
+		value interface{}
+		name  string
+		mtype string
+	}
+
+	samples := []sample{
+		{name: name("collect_duration_ms"), mtype: MetricTypeUint64, value: uint64(duration.Milliseconds())},
+		{name: name("api_calls"), mtype: MetricTypeUint64, value: stats.APICalls},
+		{name: name("samples_written"), mtype: MetricTypeUint64, value: stats.SamplesWritten},
+		{name: name("samples_discarded"), mtype: MetricTypeUint64, value: stats.SamplesDiscarded},
+		{name: name("submits"), mtype: MetricTypeUint64, value: stats.Submits},
+		{name: name("submit_errors"), mtype: MetricTypeUint64, value: stats.SubmitErrors},
+		{name: name("bytes_submitted"), mtype: MetricTypeUint64, value: stats.BytesSubmitted},
+	}
+	if stats.Submits > 0 {
+		avg := float64(stats.SubmitLatency.Milliseconds()) / float64(stats.Submits)
+		samples = append(samples, sample{name: name("submit_latency_ms"), mtype: MetricTypeFloat64, value: avg})
+	}
+
+	for _, s := range samples {
+		if err := c.writeMetricSample(metricDest, s.name, s.mtype, s.value, nil, false); err != nil {
+			return err
+		}
+	}
+	for code, n := range stats.APIErrors {
+		if err := c.writeMetricSample(metricDest, name("api_errors", Tag{Category: "code", Value: code}), MetricTypeUint64, n, nil, false); err != nil {
+			return err
+		}
+	}
+	for code, n := range stats.SubmitStatus {
+		if err := c.writeMetricSample(metricDest, name("submit_status", Tag{Category: "status", Value: strconv.Itoa(code)}), MetricTypeUint64, n, nil, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestStats(t *testing.T) {
+	t.Log("Testing Stats")
+
+	c := &Check{
+		logger:            zerolog.Nop(),
+		config:            &Config{},
+		statsMetricPrefix: "cca_",
+		metricTypeRx:      regexp.MustCompile("^[iIlLns]$"),
+	}
+
+	t.Log("sub")
+	{
+		c.RecordAPICall("", "")
+		before := c.Stats()
+		c.RecordAPICall("test", "")
+		c.RecordAPICall("test", "Throttling")
+		c.recordSample(false)
+		c.recordSample(true)
+		c.recordSubmit(100, 10*time.Millisecond, 200, false)
+
+		d := c.Stats().Sub(before)
+		if d.APICalls != 2 {
+			t.Fatalf("expected 2 api calls, got %d", d.APICalls)
+		}
+		if d.APIErrors["Throttling"] != 1 {
+			t.Fatalf("expected 1 Throttling error, got %v", d.APIErrors)
+		}
+		if d.SamplesWritten != 1 || d.SamplesDiscarded != 1 {
+			t.Fatalf("expected 1 written and 1 discarded, got %d/%d", d.SamplesWritten, d.SamplesDiscarded)
+		}
+		if d.Submits != 1 || d.BytesSubmitted != 100 || d.SubmitStatus[200] != 1 {
+			t.Fatalf("unexpected submit stats %+v", d)
+		}
+	}
+
+	t.Log("write")
+	{
+		var buf bytes.Buffer
+		before := c.Stats()
+		stats := Stats{
+			APICalls:      3,
+			APIErrors:     map[string]uint64{"AccessDenied": 1},
+			Submits:       2,
+			SubmitLatency: 30 * time.Millisecond,
+			SubmitStatus:  map[int]uint64{200: 2},
+		}
+		if err := c.WriteStats(&buf, time.Second, stats, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		out := buf.String()
+		for _, expect := range []string{
+			`{"cca_collect_duration_ms":{"_type":"L","_value":1000}}`,
+			`{"cca_api_calls":{"_type":"L","_value":3}}`,
+			`{"cca_submit_latency_ms":{"_type":"n","_value":15}}`,
+			`{"cca_api_errors|ST[`,
+			`{"cca_submit_status|ST[`,
+		} {
+			if !strings.Contains(out, expect) {
+				t.Fatalf("expected %s in\n%s", expect, out)
+			}
+		}
+		if n := c.Stats().Sub(before).SamplesWritten; n != 0 {
+			t.Fatalf("expected stats samples not counted, got %d", n)
+		}
+	}
+}
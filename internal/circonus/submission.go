@@ -0,0 +1,260 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Submission accumulates metric samples (see WriteMetricSample) for a single
+// submission to the check. By default, samples are buffered in memory and
+// submitted by Submit (see SubmitMetrics). When Config.PipeSubmits is set,
+// samples are streamed (chunked) to the broker as they are written, so memory
+// use stays flat regardless of how many samples are collected, and Submit
+// waits for the streamed submission to complete. A Submission can be reused
+// once Submit returns. NOTE: not safe for concurrent use.
+type Submission struct {
+	check *Check
+	pw    *io.PipeWriter
+	done  chan error
+	buf   bytes.Buffer
+	n     int
+}
+
+// NewSubmission returns a new, empty, submission for the check.
+func (c *Check) NewSubmission() *Submission {
+	s := &Submission{check: c}
+	if !c.config.PipeSubmits {
+		s.buf.Grow(32768)
+	}
+	return s
+}
+
+// Write metric samples to the submission.
+func (s *Submission) Write(p []byte) (int, error) {
+	if !s.check.config.PipeSubmits {
+		n, err := s.buf.Write(p)
+		s.n += n
+		return n, err
+	}
+
+	if s.pw == nil {
+		pr, pw := io.Pipe()
+		s.pw = pw
+		s.done = make(chan error, 1)
+		go func() {
+			err := s.check.streamMetrics(pr)
+			if err != nil {
+				_ = pr.CloseWithError(err)
+			} else {
+				_ = pr.Close()
+			}
+			s.done <- err
+		}()
+	}
+
+	n, err := s.pw.Write(p)
+	s.n += n
+	return n, err
+}
+
+// Len returns the number of bytes written to the submission.
+func (s *Submission) Len() int {
+	return s.n
+}
+
+// Submit the samples written. When streaming, this completes the submission
+// already in progress. The submission is reset, ready for reuse.
+func (s *Submission) Submit() error {
+	defer s.reset()
+
+	if s.pw == nil {
+		return s.check.SubmitMetrics(&s.buf)
+	}
+
+	_ = s.pw.Close()
+	return <-s.done
+}
+
+// Discard samples written but not yet submitted. When streaming, the
+// submission in progress is aborted. Discarding an empty submission is a
+// no-op, so it can be deferred to cover early returns.
+func (s *Submission) Discard() {
+	if s.pw != nil {
+		_ = s.pw.CloseWithError(errSubmissionDiscarded)
+		<-s.done
+	}
+	s.reset()
+}
+
+func (s *Submission) reset() {
+	s.buf.Reset()
+	s.pw = nil
+	s.done = nil
+	s.n = 0
+}
+
+var errSubmissionDiscarded = errors.New("submission discarded")
+
+// streamMetrics submits metrics to the broker as they are read from
+// metricSrc. The check lock is only held before and after the request, so
+// writers are not blocked by other submissions. A streamed payload cannot be
+// retried, if the submission fails and a spool is configured (Config.SpoolDir)
+// the payload, which is copied to the spool while streaming, is kept for
+// replay after the next successful submission. metricSrc is always read to
+// the end, so writers are never left blocked.
+func (c *Check) streamMetrics(metricSrc io.Reader) error {
+	defer func() { _, _ = io.Copy(io.Discard, metricSrc) }()
+
+	c.Lock()
+	if c.bundle == nil {
+		c.Unlock()
+		return errors.New("invalid state (nil check bundle)")
+	}
+	subURL, found := c.bundle.Config["submission_url"]
+	if !found {
+		c.Unlock()
+		return errors.New("invalid check bundle, no submission url")
+	}
+	// share the connection pool, but not the timeout, a streamed
+	// submission lasts as long as the collection writing to it
+	client := &http.Client{Transport: c.httpClient().Transport}
+	var sw *spoolWriter
+	if c.spool != nil {
+		var err error
+		if sw, err = c.spool.create(); err != nil {
+			c.logger.Warn().Err(err).Msg("unable to spool streamed submission")
+			sw = nil
+		}
+	}
+	c.Unlock()
+
+	src := metricSrc
+	if sw != nil {
+		src = io.TeeReader(src, sw)
+	}
+	src, traceDone := c.traceMetrics(src, "Streaming data")
+	defer traceDone()
+
+	body, encoding := c.compressStream(src)
+	sent := &lockedReader{r: body}
+
+	respBody, err := c.put(client, subURL, sent, encoding, sent.count)
+
+	// whatever the request did not send (e.g. it failed) still needs to be
+	// read, to complete the spooled copy and unblock the writer
+	_, drainErr := io.Copy(io.Discard, sent)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if errors.Is(drainErr, errSubmissionDiscarded) {
+		if sw != nil {
+			sw.discard()
+		}
+		c.logger.Debug().Msg("streamed submission discarded")
+		return nil
+	}
+
+	if err != nil {
+		if c.refreshOnError(err) {
+			c.logger.Warn().Msg("streamed submission cannot be resubmitted")
+		}
+		var serr *submitError
+		if sw == nil || !errors.As(err, &serr) || !serr.retryable() {
+			if sw != nil {
+				sw.discard()
+			}
+			return errors.Wrap(err, "submitting metrics")
+		}
+		if spoolErr := sw.commit(); spoolErr != nil {
+			c.logger.Error().Err(spoolErr).Msg("spooling failed submission")
+			return errors.Wrap(err, "submitting metrics")
+		}
+		c.logger.Warn().Err(err).Int64("bytes", sw.size).Msg("submission failed, payload spooled for later delivery")
+		return nil
+	}
+
+	if sw != nil {
+		sw.discard()
+	}
+
+	c.logger.Debug().Str("cid", c.bundle.CID).RawJSON("result", respBody).Msg("telemetry stats streamed")
+	c.consecutive5xx = 0
+
+	if c.spool != nil {
+		replayed, err := c.spool.replay(c.submit)
+		if replayed > 0 {
+			c.logger.Info().Int("payloads", replayed).Msg("replayed spooled submissions")
+		}
+		if err != nil {
+			c.logger.Warn().Err(err).Msg("replaying spooled submissions")
+		}
+	}
+
+	return nil
+}
+
+// compressStream is the streaming counterpart of compress. Returns the
+// request body and the Content-Encoding to use, which is empty if the
+// payload is not compressed.
+func (c *Check) compressStream(src io.Reader) (io.Reader, string) {
+	var newWriter func(io.Writer) io.WriteCloser
+	switch c.config.Compression {
+	case CompressionGzip:
+		newWriter = func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	case CompressionDeflate:
+		newWriter = func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) } // http 'deflate' is zlib format (rfc 1950)
+	default:
+		return src, ""
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w := newWriter(pw)
+		_, err := io.Copy(w, src)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = pw.CloseWithError(errors.Wrapf(err, "compressing payload (%s)", c.config.Compression))
+			return
+		}
+		_ = pw.Close()
+	}()
+
+	return pr, c.config.Compression
+}
+
+// lockedReader serializes reads of a request body, the http transport may
+// still be reading it after the request returns (e.g. on error) while the
+// remainder is drained, and counts the bytes read.
+type lockedReader struct {
+	r io.Reader
+	n int
+	sync.Mutex
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+	n, err := l.r.Read(p)
+	l.n += n
+	return n, err
+}
+
+func (l *lockedReader) count() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.n
+}
@@ -0,0 +1,170 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/rs/zerolog"
+)
+
+func TestSubmission(t *testing.T) {
+	t.Log("Testing Submission")
+
+	var mu sync.Mutex
+	fail := false
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == CompressionGzip {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		data, err := io.ReadAll(body)
+		if err != nil { // aborted (e.g. discarded) streamed submission
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, string(data))
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	spool, err := newSpool(t.TempDir(), "test", 1024*1024, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	c := &Check{
+		config:       &Config{PipeSubmits: true, Compression: CompressionGzip},
+		bundle:       &apiclient.CheckBundle{Config: apiclient.CheckBundleConfig{"submission_url": ts.URL}},
+		spool:        spool,
+		logger:       zerolog.Nop(),
+		metricTypeRx: regexp.MustCompile("^[iIlLns]$"),
+	}
+
+	sub := c.NewSubmission()
+
+	t.Log("streamed")
+	{
+		for i := 0; i < 1000; i++ {
+			if err := c.WriteMetricSample(sub, "foo", MetricTypeUint64, uint64(i), nil); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+		if err := sub.Submit(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if sub.Len() != 0 {
+			t.Fatalf("expected submission to be reset, len %d", sub.Len())
+		}
+		if len(received) != 1 {
+			t.Fatalf("expected 1 submission, got %d", len(received))
+		}
+		if n := strings.Count(received[0], "\n"); n != 1000 {
+			t.Fatalf("expected 1000 samples, got %d", n)
+		}
+		if s := c.Stats(); s.Submits != 1 || s.SubmitErrors != 0 {
+			t.Fatalf("unexpected submit stats %+v", s)
+		}
+	}
+
+	t.Log("failed, spooled")
+	{
+		mu.Lock()
+		fail = true
+		mu.Unlock()
+		if err := c.WriteMetricSample(sub, "bar", MetricTypeUint64, 1, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := sub.Submit(); err != nil {
+			t.Fatalf("expected no error (spooled), got (%s)", err)
+		}
+		files, _, err := spool.files()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 spooled payload, got %d", len(files))
+		}
+		data, _ := os.ReadFile(files[0].path)
+		if !strings.Contains(string(data), `"bar"`) {
+			t.Fatalf("expected spooled sample, got %s", data)
+		}
+	}
+
+	t.Log("discarded")
+	{
+		if err := c.WriteMetricSample(sub, "baz", MetricTypeUint64, 1, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		sub.Discard()
+		if _, total, _ := spool.files(); total == 0 {
+			t.Fatal("expected spooled payload to remain")
+		}
+		if files, _, _ := spool.files(); len(files) != 1 {
+			t.Fatalf("expected discarded payload not to be spooled, got %d", len(files))
+		}
+	}
+
+	t.Log("success replays spool")
+	{
+		mu.Lock()
+		fail = false
+		received = nil
+		mu.Unlock()
+		if err := c.WriteMetricSample(sub, "qux", MetricTypeUint64, 1, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := sub.Submit(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(received) != 2 || !strings.Contains(received[1], `"bar"`) {
+			t.Fatalf("expected streamed submission then spooled payload, got %v", received)
+		}
+		if files, _, _ := spool.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d", len(files))
+		}
+	}
+
+	t.Log("buffered")
+	{
+		c.config.PipeSubmits = false
+		received = nil
+		sub := c.NewSubmission()
+		if err := c.WriteMetricSample(sub, "foo", MetricTypeUint64, 1, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if sub.Len() == 0 {
+			t.Fatal("expected buffered samples")
+		}
+		if err := sub.Submit(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(received) != 1 {
+			t.Fatalf("expected 1 submission, got %d", len(received))
+		}
+	}
+}
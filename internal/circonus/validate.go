@@ -0,0 +1,59 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+)
+
+var (
+	checkBundleCIDRx = regexp.MustCompile(`^/check_bundle/[0-9]+$`)
+	brokerCIDRx      = regexp.MustCompile(`^/broker/[0-9]+$`)
+)
+
+// Validate returns the problems found with the Circonus configuration of a
+// service instance, without making any Circonus API calls.
+func (sc ServiceConfig) Validate() []error {
+	var errs []error
+
+	if sc.Key == "" {
+		errs = append(errs, config.FieldErrorf("key", "required"))
+	}
+	if sc.CID != "" && !checkBundleCIDRx.MatchString(sc.CID) {
+		errs = append(errs, config.FieldErrorf("cid", "invalid check bundle cid (%s), expected /check_bundle/<id>", sc.CID))
+	}
+	if sc.BrokerCID != "" && !brokerCIDRx.MatchString(sc.BrokerCID) {
+		errs = append(errs, config.FieldErrorf("broker_cid", "invalid broker cid (%s), expected /broker/<id>", sc.BrokerCID))
+	}
+	if sc.URL != "" {
+		if u, err := url.Parse(sc.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, config.FieldErrorf("url", "invalid url (%s)", sc.URL))
+		}
+	}
+	if sc.CAFile != "" {
+		if _, err := os.Stat(sc.CAFile); err != nil {
+			errs = append(errs, config.FieldErrorf("ca_file", "%s", err))
+		}
+	}
+	switch sc.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionDeflate:
+	default:
+		errs = append(errs, config.FieldErrorf("compression", "invalid compression (%s), expected none, gzip, or deflate", sc.Compression))
+	}
+
+	for i, f := range sc.MetricFilters {
+		if err := f.Validate(); err != nil {
+			errs = append(errs, config.FieldErrorf(fmt.Sprintf("metric_filters[%d]", i), "%s", err))
+		}
+	}
+
+	return errs
+}
@@ -25,6 +25,35 @@ type Log struct {
 	Pretty bool   `json:"pretty" yaml:"pretty" toml:"pretty"`
 }
 
+// Submit defines the running config.submit structure.
+type Submit struct {
+	SpoolDir     string `mapstructure:"spool_dir" json:"spool_dir" yaml:"spool_dir" toml:"spool_dir"`
+	Timeout      string `json:"timeout" yaml:"timeout" toml:"timeout"`
+	IdleTimeout  string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`
+	Retries      int    `json:"retries" yaml:"retries" toml:"retries"`
+	SpoolMaxMB   int    `mapstructure:"spool_max_mb" json:"spool_max_mb" yaml:"spool_max_mb" toml:"spool_max_mb"`
+	MaxConns     int    `mapstructure:"max_conns" json:"max_conns" yaml:"max_conns" toml:"max_conns"`
+	MaxIdleConns int    `mapstructure:"max_idle_conns" json:"max_idle_conns" yaml:"max_idle_conns" toml:"max_idle_conns"`
+}
+
+// State defines the running config.state structure.
+type State struct {
+	File        string `json:"file" yaml:"file" toml:"file"`
+	MaxLookback string `mapstructure:"max_lookback" json:"max_lookback" yaml:"max_lookback" toml:"max_lookback"`
+}
+
+// Dedup defines the running config.dedup structure.
+type Dedup struct {
+	RevisionWindow string `mapstructure:"revision_window" json:"revision_window" yaml:"revision_window" toml:"revision_window"`
+	Enabled        bool   `json:"enabled" yaml:"enabled" toml:"enabled"`
+}
+
+// Check defines the running config.check structure.
+type Check struct {
+	Reconcile      string `json:"reconcile" yaml:"reconcile" toml:"reconcile"`
+	MultipleActive string `mapstructure:"multiple_active" json:"multiple_active" yaml:"multiple_active" toml:"multiple_active"`
+}
+
 // // API defines the running config.api structure
 // type API struct {
 // 	App    string `json:"app" yaml:"app" toml:"app"`
@@ -40,26 +69,34 @@ type Config struct {
 	Azure       *AzureConfig `json:"azure" toml:"azure" yaml:"azure"`
 	GCP         *GCPConfig   `json:"gcp" toml:"gcp" yaml:"gcp"`
 	Log         Log          `json:"log" yaml:"log" toml:"log"`
+	Submit      Submit       `json:"submit" yaml:"submit" toml:"submit"`
+	State       State        `json:"state" yaml:"state" toml:"state"`
+	Dedup       Dedup        `json:"dedup" yaml:"dedup" toml:"dedup"`
+	Check       Check        `json:"check" yaml:"check" toml:"check"`
+	Listen      string       `json:"listen" yaml:"listen" toml:"listen"`
 	Debug       bool         `json:"debug" yaml:"debug" toml:"debug"`
 	PipeSubmits bool         `json:"pipe_submits" toml:"pipe_submits" yaml:"pipe_submits"`
 }
 
 // AWSConfig defines the AWS cloud service configuration.
 type AWSConfig struct {
-	ConfDir string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
-	Enabled bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	ConfDir      string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
+	Enabled      bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	WatchConfDir bool   `json:"watch_conf_dir" toml:"watch_conf_dir" yaml:"watch_conf_dir"`
 }
 
 // AzureConfig defines the Azure cloud service configuration.
 type AzureConfig struct {
-	ConfDir string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
-	Enabled bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	ConfDir      string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
+	Enabled      bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	WatchConfDir bool   `json:"watch_conf_dir" toml:"watch_conf_dir" yaml:"watch_conf_dir"`
 }
 
 // GCPConfig defines the AWS cloud service configuration.
 type GCPConfig struct {
-	ConfDir string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
-	Enabled bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	ConfDir      string `json:"conf_dir" toml:"conf_dir" yaml:"conf_dir"`
+	Enabled      bool   `json:"enabled" toml:"enabled" yaml:"enabled"`
+	WatchConfDir bool   `json:"watch_conf_dir" toml:"watch_conf_dir" yaml:"watch_conf_dir"`
 }
 
 // NOTE: adding a Key* MUST be reflected in the Config structures above.
@@ -84,20 +121,68 @@ const (
 	// KeyDebug enables debug messages.
 	KeyDebug = "debug"
 
+	// KeyListen address for the http status server (/health, /status, /debug/vars).
+	KeyListen = "listen"
+
 	// KeyLogLevel logging level (panic, fatal, error, warn, info, debug, disabled).
 	KeyLogLevel = "log.level"
 
 	// KeyLogPretty output formatted log lines (for running in foreground).
 	KeyLogPretty = "log.pretty"
 
+	// KeySubmitTimeout for a metric submission request to a broker.
+	KeySubmitTimeout = "submit.timeout"
+
+	// KeySubmitIdleTimeout how long idle broker connections are kept for reuse.
+	KeySubmitIdleTimeout = "submit.idle_timeout"
+
+	// KeySubmitMaxConns maximum connections to a broker, per check.
+	KeySubmitMaxConns = "submit.max_conns"
+
+	// KeySubmitMaxIdleConns maximum idle connections kept to a broker for reuse, per check.
+	KeySubmitMaxIdleConns = "submit.max_idle_conns"
+
+	// KeySubmitRetries number of times to retry a failed metric submission.
+	KeySubmitRetries = "submit.retries"
+
+	// KeySubmitSpoolDir directory to spool metric submissions which failed after retries (disabled if empty).
+	KeySubmitSpoolDir = "submit.spool_dir"
+
+	// KeySubmitSpoolMaxMB maximum size of the spool, per check, in megabytes.
+	KeySubmitSpoolMaxMB = "submit.spool_max_mb"
+
+	// KeyStateFile file the end of the last successful collection, per instance collector, is persisted to (disabled if empty).
+	KeyStateFile = "state.file"
+
+	// KeyStateMaxLookback maximum time range collected after a restart, when resuming from the state file.
+	KeyStateMaxLookback = "state.max_lookback"
+
+	// KeyDedup skip samples already delivered for a series (metric name including stream tags).
+	KeyDedup = "dedup.enabled"
+
+	// KeyDedupRevisionWindow samples within this window of the last delivered for a series are delivered again (late arriving data).
+	KeyDedupRevisionWindow = "dedup.revision_window"
+
+	// KeyCheckReconcile how existing check bundles are reconciled with the configuration (update|log|off).
+	KeyCheckReconcile = "check.reconcile"
+
+	// KeyCheckMultipleActive how multiple active check bundles found for an instance are resolved (error|oldest|notes|deactivate).
+	KeyCheckMultipleActive = "check.multiple_active"
+
 	// KeyShowConfig - show configuration and exit.
 	KeyShowConfig = "show-config"
 
 	// KeyShowVersion - show version information and exit.
 	KeyShowVersion = "version"
 
-	// KeyPipeSubmits - use io pipe for metric submissions (experimental)
-	// KeyPipeSubmits = "pipe_submits".
+	// KeyDryRun - run each collection once, without Circonus API calls, write the submissions and exit.
+	KeyDryRun = "dry-run"
+
+	// KeyDryRunOutput - file dry run submissions are written to (default stdout).
+	KeyDryRunOutput = "dry-run-output"
+
+	// KeyPipeSubmits - stream metric submissions to the broker through an io pipe while collecting.
+	KeyPipeSubmits = "pipe_submits"
 )
 
 var (
@@ -105,17 +190,6 @@ var (
 	MetricNameSeparator = defaults.MetricNameSeparator // var, TBD whether it will become configurable
 )
 
-// Validate verifies the required portions of the configuration.
-func Validate() error {
-
-	// err := validateAPIOptions()
-	// if err != nil {
-	// 	return errors.Wrap(err, "API config")
-	// }
-
-	return nil
-}
-
 // StatConfig adds the running config to the app stats.
 func StatConfig() error {
 	cfg, err := getConfig()
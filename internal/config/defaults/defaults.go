@@ -29,11 +29,54 @@ const (
 	// MetricNameSeparator defines character used to delimit metric name parts.
 	MetricNameSeparator = "`"
 
+	// Listen address for the status server, disabled by default.
+	Listen = ""
+
 	// LogLevel set to info by default.
 	LogLevel = "info"
 
 	// LogPretty colored/formatted output to stderr.
 	LogPretty = false
+
+	// SubmitTimeout for metric submission requests.
+	SubmitTimeout = "60s"
+
+	// SubmitIdleTimeout for idle broker connections.
+	SubmitIdleTimeout = "90s"
+
+	// SubmitMaxConns per check.
+	SubmitMaxConns = 4
+
+	// SubmitMaxIdleConns per check.
+	SubmitMaxIdleConns = 2
+
+	// SubmitRetries for failed metric submissions.
+	SubmitRetries = 3
+
+	// SubmitSpoolDir for failed metric submissions, disabled by default
+	// (e.g. /opt/circonus/cloud-agent/spool).
+	SubmitSpoolDir = ""
+
+	// SubmitSpoolMaxMB per check.
+	SubmitSpoolMaxMB = 100
+
+	// StateMaxLookback maximum time range collected after a restart.
+	StateMaxLookback = "3h"
+
+	// Dedup skipping samples already delivered is disabled by default.
+	Dedup = false
+
+	// DedupRevisionWindow samples are not delivered again by default.
+	DedupRevisionWindow = "0s"
+
+	// CheckReconcile existing check bundles are updated to match the configuration.
+	CheckReconcile = "update"
+
+	// CheckMultipleActive an instance with multiple active check bundles is not started.
+	CheckMultipleActive = "error"
+
+	// PipeSubmits streams metric submissions while collecting.
+	PipeSubmits = false
 )
 
 var (
@@ -51,6 +94,9 @@ var (
 
 	// EtcPath returns the default etc directory within base directory.
 	EtcPath = "" // (e.g. /opt/circonus/cloud-agent/etc)
+
+	// StateFile defines the default file collection watermarks are persisted to.
+	StateFile = "" // (e.g. /opt/circonus/cloud-agent/state/watermarks.json)
 )
 
 func init() {
@@ -73,4 +119,5 @@ func init() {
 
 	EtcPath = filepath.Join(BasePath, "etc")
 	ConfigFile = filepath.Join(EtcPath, release.NAME+".yaml")
+	StateFile = filepath.Join(BasePath, "state", "watermarks.json")
 }
@@ -6,6 +6,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -21,6 +22,17 @@ import (
 // `target` is an interface in to which the data will be loaded. Checks for
 // '<base>.json', '<base>.toml', and '<base>.yaml'.
 func LoadConfigFile(base string, target interface{}) error {
+	return loadConfigFile(base, target, false)
+}
+
+// LoadConfigFileStrict loads a configuration file like LoadConfigFile, but
+// keys which do not correspond to a field in target are errors (e.g. a
+// misspelled option which would otherwise be silently ignored).
+func LoadConfigFileStrict(base string, target interface{}) error {
+	return loadConfigFile(base, target, true)
+}
+
+func loadConfigFile(base string, target interface{}, strict bool) error {
 
 	if base == "" {
 		return errors.Errorf("invalid config file (empty)")
@@ -56,17 +68,25 @@ func LoadConfigFile(base string, target interface{}) error {
 		parseErrMsg := fmt.Sprintf("parsing configuration file (%s)", cfg)
 		switch ext {
 		case ".json":
-			if err := json.Unmarshal(data, target); err != nil {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			if strict {
+				dec.DisallowUnknownFields()
+			}
+			if err := dec.Decode(target); err != nil {
 				return errors.Wrap(err, parseErrMsg)
 			}
 			loaded = true
 		case ".toml":
-			if err := toml.Unmarshal(data, target); err != nil {
+			if err := toml.NewDecoder(bytes.NewReader(data)).Strict(strict).Decode(target); err != nil {
 				return errors.Wrap(err, parseErrMsg)
 			}
 			loaded = true
 		case ".yaml":
-			if err := yaml.Unmarshal(data, target); err != nil {
+			unmarshal := yaml.Unmarshal
+			if strict {
+				unmarshal = yaml.UnmarshalStrict
+			}
+			if err := unmarshal(data, target); err != nil {
 				return errors.Wrap(err, parseErrMsg)
 			}
 			loaded = true
@@ -0,0 +1,124 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// FieldError is a problem with a configuration field. Field is the path of
+// the field in the configuration file (e.g. regions[0].services[1].namespace).
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return e.Field + ": " + e.Msg
+}
+
+// FieldErrorf returns a FieldError for field.
+func FieldErrorf(field, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+// PrefixFieldErrors returns errs with prefix prepended to the field of each
+// FieldError, used for nested configuration (e.g. sinks[0]). Fields which
+// are list indexes (e.g. [1].name) are appended without a separator.
+func PrefixFieldErrors(prefix string, errs []error) []error {
+	for i, err := range errs {
+		var fe *FieldError
+		if !errors.As(err, &fe) {
+			errs[i] = FieldErrorf(prefix, "%s", err)
+			continue
+		}
+		field := prefix
+		switch {
+		case fe.Field == "":
+		case strings.HasPrefix(fe.Field, "["):
+			field += fe.Field
+		default:
+			field += "." + fe.Field
+		}
+		errs[i] = &FieldError{Field: field, Msg: fe.Msg}
+	}
+	return errs
+}
+
+// ValidateConfigFile checks the main configuration file, keys which are not
+// options (e.g. misspelled) and values of the wrong type are errors. The
+// option values are checked by Validate.
+func ValidateConfigFile(file string) []error {
+	var cfg Config
+	if err := LoadConfigFileStrict(file, &cfg); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// Validate verifies the running configuration, returns an error for the
+// first invalid option (e.g. a duration which cannot be parsed).
+func Validate() error {
+	if errs := ValidateOptions(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateOptions returns all of the problems found with the running
+// configuration options (see Validate).
+func ValidateOptions() []error {
+	var errs []error
+
+	switch level := viper.GetString(KeyLogLevel); level {
+	case "", "panic", "fatal", "error", "warn", "info", "debug", "disabled":
+	default:
+		errs = append(errs, FieldErrorf(KeyLogLevel, "unknown log level (%s)", level))
+	}
+
+	switch mode := viper.GetString(KeyCheckReconcile); mode {
+	case "", "update", "log", "off":
+	default:
+		errs = append(errs, FieldErrorf(KeyCheckReconcile, "invalid mode (%s), expected update, log, or off", mode))
+	}
+
+	switch strategy := viper.GetString(KeyCheckMultipleActive); strategy {
+	case "", "error", "oldest", "notes", "deactivate":
+	default:
+		errs = append(errs, FieldErrorf(KeyCheckMultipleActive, "invalid strategy (%s), expected error, oldest, notes, or deactivate", strategy))
+	}
+
+	for _, key := range []string{KeySubmitTimeout, KeySubmitIdleTimeout, KeyStateMaxLookback, KeyDedupRevisionWindow} {
+		v := viper.GetString(key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, FieldErrorf(key, "invalid duration (%s)", v))
+			continue
+		}
+		if d < 0 {
+			errs = append(errs, FieldErrorf(key, "invalid duration (%s), must not be negative", v))
+		}
+	}
+
+	for _, key := range []string{KeySubmitRetries, KeySubmitSpoolMaxMB, KeySubmitMaxConns, KeySubmitMaxIdleConns} {
+		if viper.GetInt(key) < 0 {
+			errs = append(errs, FieldErrorf(key, "invalid value (%d), must not be negative", viper.GetInt(key)))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,96 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+func TestValidateOptions(t *testing.T) {
+	t.Log("Testing ValidateOptions")
+
+	t.Log("\tinvalid duration")
+	{
+		viper.Set(KeySubmitTimeout, "ten seconds")
+		defer viper.Set(KeySubmitTimeout, nil)
+		errs := ValidateOptions()
+		if len(errs) != 1 {
+			t.Fatalf("expected 1 error, got %v", errs)
+		}
+		if !strings.HasPrefix(errs[0].Error(), KeySubmitTimeout+": ") {
+			t.Fatalf("expected error for %s, got (%s)", KeySubmitTimeout, errs[0])
+		}
+		if err := Validate(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestPrefixFieldErrors(t *testing.T) {
+	t.Log("Testing PrefixFieldErrors")
+
+	errs := PrefixFieldErrors("regions", []error{
+		FieldErrorf("[0].name", "required"),
+		FieldErrorf("services", "required"),
+		errors.New("not a field error"),
+	})
+	expect := []string{
+		"regions[0].name: required",
+		"regions.services: required",
+		"regions: not a field error",
+	}
+	for i, e := range expect {
+		if errs[i].Error() != e {
+			t.Fatalf("expected (%s), got (%s)", e, errs[i])
+		}
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	t.Log("Testing ValidateConfigFile")
+
+	dir := t.TempDir()
+
+	t.Log("\tvalid")
+	{
+		file := filepath.Join(dir, "valid.yaml")
+		if err := os.WriteFile(file, []byte("log:\n  level: debug\n"), 0600); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if errs := ValidateConfigFile(file); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	}
+
+	t.Log("\tunknown key")
+	{
+		file := filepath.Join(dir, "unknown.yaml")
+		if err := os.WriteFile(file, []byte("log:\n  levle: debug\n"), 0600); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		errs := ValidateConfigFile(file)
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "levle") {
+			t.Fatalf("expected unknown key error, got %v", errs)
+		}
+	}
+
+	t.Log("\twrong type")
+	{
+		file := filepath.Join(dir, "type.json")
+		if err := os.WriteFile(file, []byte(`{"debug":"yes"}`), 0600); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if errs := ValidateConfigFile(file); len(errs) != 1 {
+			t.Fatalf("expected type error, got %v", errs)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package server provides the agent's http status endpoints.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/telemetry"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Server defines the http status server.
+type Server struct {
+	ctx      context.Context
+	svr      *http.Server
+	services map[string]services.Service
+	started  time.Time
+	logger   zerolog.Logger
+}
+
+// Status defines the document returned by the /status endpoint.
+type Status struct {
+	Started  time.Time                            `json:"started"`
+	Services map[string][]services.InstanceStatus `json:"services"`
+	Name     string                               `json:"name"`
+	Version  string                               `json:"version"`
+	Uptime   string                               `json:"uptime"`
+}
+
+// Health defines the document returned by the /health endpoint.
+type Health struct {
+	Status    string `json:"status"`
+	Instances int    `json:"instances"`
+	Running   int    `json:"running"`
+}
+
+const (
+	healthOK          = "ok"
+	healthNoInstances = "no active instances"
+)
+
+// New returns a new status server if a listen address is configured, otherwise nil.
+func New(ctx context.Context, svcs map[string]services.Service) (*Server, error) {
+	addr := viper.GetString(config.KeyListen)
+	if addr == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, errors.Wrapf(err, "invalid listen address (%s)", addr)
+	}
+
+	s := &Server{
+		ctx:      ctx,
+		services: svcs,
+		started:  time.Now(),
+		logger:   log.With().Str("pkg", "server").Logger(),
+	}
+
+	s.svr = &http.Server{
+		Addr:              addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	return s, nil
+}
+
+// Start the status server, intended to be run in a goroutine (e.g. errgroup).
+func (s *Server) Start() error {
+	if s == nil {
+		return nil
+	}
+
+	go func() {
+		<-s.ctx.Done()
+		s.Stop()
+	}()
+
+	s.logger.Info().Str("listen", s.svr.Addr).Msg("starting status server")
+
+	if err := s.svr.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "status server")
+	}
+
+	return nil
+}
+
+// Stop the status server.
+func (s *Server) Stop() {
+	if s == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.svr.Shutdown(ctx); err != nil {
+		s.logger.Warn().Err(err).Msg("stopping status server")
+	}
+}
+
+// handler returns the router for the status endpoints.
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/health", s.health)
+	mux.HandleFunc("/status", s.status)
+	mux.Handle("/metrics", telemetry.Handler())
+	return mux
+}
+
+// health responds with 200 when at least one collection instance is active,
+// otherwise 503 (e.g. all instance configurations were removed).
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	h := Health{Status: healthOK}
+	for _, svc := range s.services {
+		for _, inst := range svc.Status() {
+			h.Instances++
+			if inst.Running {
+				h.Running++
+			}
+		}
+	}
+
+	code := http.StatusOK
+	if h.Instances == 0 {
+		h.Status = healthNoInstances
+		code = http.StatusServiceUnavailable
+	}
+
+	s.writeJSON(w, code, h)
+}
+
+// status responds with the state of every service instance.
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	st := Status{
+		Name:     release.NAME,
+		Version:  release.VERSION,
+		Started:  s.started,
+		Uptime:   time.Since(s.started).Round(time.Second).String(),
+		Services: make(map[string][]services.InstanceStatus),
+	}
+
+	for svcID, svc := range s.services {
+		instances := svc.Status()
+		sort.Slice(instances, func(i, j int) bool {
+			if instances[i].ID != instances[j].ID {
+				return instances[i].ID < instances[j].ID
+			}
+			return instances[i].Region < instances[j].Region
+		})
+		st.Services[svcID] = instances
+	}
+
+	s.writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		s.logger.Error().Err(err).Msg("encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Warn().Err(err).Msg("writing response")
+	}
+}
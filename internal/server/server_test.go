@@ -0,0 +1,166 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+type testService struct {
+	status []services.InstanceStatus
+}
+
+func (ts *testService) Enabled() bool                                              { return true }
+func (ts *testService) Scan() error                                                { return nil }
+func (ts *testService) Start() error                                               { return nil }
+func (ts *testService) Status() []services.InstanceStatus                          { return ts.status }
+func (ts *testService) CollectOnce(services.TimeRange) []services.CollectionResult { return nil }
+
+func TestNew(t *testing.T) {
+	t.Log("Testing New")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("not configured")
+	{
+		viper.Set(config.KeyListen, "")
+		s, err := New(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if s != nil {
+			t.Fatal("expected nil server")
+		}
+	}
+
+	t.Log("invalid address")
+	{
+		viper.Set(config.KeyListen, "localhost")
+		if _, err := New(context.Background(), nil); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid")
+	{
+		viper.Set(config.KeyListen, "127.0.0.1:0")
+		s, err := New(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if s == nil {
+			t.Fatal("expected server")
+		}
+	}
+
+	viper.Set(config.KeyListen, "")
+}
+
+func TestHandlers(t *testing.T) {
+	t.Log("Testing handlers")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	svc := &testService{}
+	viper.Set(config.KeyListen, "127.0.0.1:0")
+	s, err := New(context.Background(), map[string]services.Service{"aws": svc})
+	viper.Set(config.KeyListen, "")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	h := s.handler()
+
+	t.Log("health, no instances")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	}
+
+	svc.status = []services.InstanceStatus{
+		{ID: "b", Region: "us-east-1", Running: true},
+		{ID: "a", Region: "us-west-2", Collectors: []services.CollectorStatus{{ID: "AWS/EC2", Enabled: false, DisableCause: "denied"}}},
+	}
+
+	t.Log("health")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+		}
+		var health Health
+		if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if health.Instances != 2 || health.Running != 1 {
+			t.Fatalf("unexpected health (%+v)", health)
+		}
+	}
+
+	t.Log("status")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+		}
+		var st Status
+		if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		instances := st.Services["aws"]
+		if len(instances) != 2 {
+			t.Fatalf("expected 2 instances, got %d", len(instances))
+		}
+		if instances[0].ID != "a" {
+			t.Fatalf("expected sorted instances, got (%+v)", instances)
+		}
+		if instances[0].Collectors[0].DisableCause != "denied" {
+			t.Fatalf("expected disable cause, got (%+v)", instances[0].Collectors)
+		}
+	}
+
+	t.Log("expvar")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	t.Log("metrics")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Fatalf("expected text/plain, got (%s)", ct)
+		}
+	}
+
+	t.Log("method not allowed")
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	}
+}
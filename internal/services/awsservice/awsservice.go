@@ -12,8 +12,11 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"sync"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice/collectors"
 	toml "github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
@@ -33,6 +36,11 @@ const (
 	// KeyConfDir defines the aws configuration directory.
 	KeyConfDir = "aws.conf_dir"
 
+	// KeyWatchConfDir toggles watching the AWS configuration directory for changes.
+	KeyWatchConfDir = "aws.watch_conf_dir"
+	// DefaultWatchConfDir defines the default setting.
+	DefaultWatchConfDir = true
+
 	// KeyConfExample shows an example configuration.
 	KeyConfExample = "aws.config_example"
 )
@@ -46,9 +54,15 @@ var (
 type AWSService struct {
 	groupCtx  context.Context
 	group     *errgroup.Group
+	confDir   string
 	instances []*Instance
+	scanMu    sync.Mutex
 	logger    zerolog.Logger
+	sync.Mutex
 	enabled   bool
+	started   bool
+	watch     bool
+	stateless bool // one-off collections which do not use the state file (see NewFromFile)
 }
 
 // New returns an AWS cloud service metric collector.
@@ -63,6 +77,7 @@ func New(ctx context.Context) (*AWSService, error) {
 	g, gctx := errgroup.WithContext(ctx)
 	svc := AWSService{
 		enabled:   viper.GetBool(KeyEnabled),
+		watch:     viper.GetBool(KeyWatchConfDir),
 		group:     g,
 		groupCtx:  gctx,
 		instances: make([]*Instance, 0),
@@ -75,12 +90,12 @@ func New(ctx context.Context) (*AWSService, error) {
 
 	svc.logger.Debug().Msg("AWS enabled, initializing client")
 
-	confDir := viper.GetString(KeyConfDir)
-	if confDir == "" {
-		confDir = DefaultConfDir
+	svc.confDir = viper.GetString(KeyConfDir)
+	if svc.confDir == "" {
+		svc.confDir = DefaultConfDir
 	}
 
-	if err := svc.initInstances(confDir); err != nil {
+	if err := svc.initInstances(svc.confDir); err != nil {
 		return nil, errors.Wrap(err, "initializing AWS metric collector instances(s)")
 	}
 
@@ -92,14 +107,188 @@ func New(ctx context.Context) (*AWSService, error) {
 	return &svc, nil
 }
 
+// NewFromFile returns an AWS service with the instances (one per region)
+// defined by a single configuration file, for one-off collections (see
+// CollectOnce). The service is enabled regardless of the aws.enabled
+// setting, and does not watch for configuration changes. The state file
+// (collection watermarks) is only used if resume is true.
+func NewFromFile(ctx context.Context, cfgFile string, resume bool) (*AWSService, error) {
+	if cfgFile == "" {
+		return nil, errors.New("invalid config file (empty)")
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	svc := AWSService{
+		enabled:   true,
+		stateless: !resume,
+		group:     g,
+		groupCtx:  gctx,
+		confDir:   filepath.Dir(cfgFile),
+		logger:    log.With().Str("pkg", "aws").Logger(),
+	}
+
+	instances, _, err := svc.instancesFromConfig(cfgFile, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing AWS metric collector instance(s)")
+	}
+	svc.instances = instances
+
+	return &svc, nil
+}
+
 // Enabled indicates whether the AWS service is enabled.
 func (svc *AWSService) Enabled() bool {
 	return svc.enabled
 }
 
 // Scan checks the service config directory for configurations and loads them.
+// Instances for new configuration files are started, instances whose configuration
+// file was removed are stopped, and instances whose configuration file changed are
+// restarted. Instances with unchanged configurations are not affected.
 func (svc *AWSService) Scan() error {
-	return errors.New("not implemented")
+	if !svc.enabled {
+		svc.logger.Info().Msg("AWS client disabled, not checking for configurations")
+		return nil
+	}
+	svc.logger.Info().Str("conf_dir", svc.confDir).Msg("AWS client checking for configuration(s)")
+
+	// serialize scans (e.g. SIGHUP and config dir watcher), the service lock
+	// is only held while reading/updating the list of instances so that
+	// status requests are not blocked while new instances are initialized
+	svc.scanMu.Lock()
+	defer svc.scanMu.Unlock()
+
+	files, err := services.ConfigFiles(svc.confDir)
+	if err != nil {
+		return errors.Wrap(err, "scanning AWS config dir")
+	}
+
+	svc.Lock()
+	existingInstances := svc.instances
+	svc.Unlock()
+
+	// current instances, grouped by the configuration file they were created from
+	// (one config file may define multiple regions, one instance per region)
+	current := make(map[string][]*Instance)
+	for _, inst := range existingInstances {
+		current[inst.cfgFile] = append(current[inst.cfgFile], inst)
+	}
+
+	instances := make([]*Instance, 0, len(existingInstances))
+	created := make([]*Instance, 0)
+	stopped := make([]*Instance, 0)
+	seen := make(map[string]bool)
+
+	for _, cf := range files {
+		seen[cf.Path] = true
+		existing, found := current[cf.Path]
+		if found && unchanged(existing, cf.Hash) {
+			instances = append(instances, existing...)
+			continue
+		}
+
+		// create the replacement instance(s) before stopping the existing ones, so
+		// that an invalid configuration does not stop a running collection instance
+		newInstances, failed, err := svc.instancesFromConfig(cf.Path, cf.Hash)
+		if err != nil {
+			svc.logger.Error().Err(err).Str("config_file", cf.Path).Msg("loading config, skipping")
+			instances = append(instances, existing...)
+			continue
+		}
+
+		if found {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("config changed, restarting instance(s)")
+			// keep the existing instance of a region whose replacement failed
+			// to initialize, it is retried the next time the config is scanned
+			for _, inst := range existing {
+				if failed[inst.regionCfg.Name] {
+					svc.logger.Warn().Str("config_file", cf.Path).Str("region", inst.regionCfg.Name).Msg("keeping existing instance for region")
+					instances = append(instances, inst)
+					continue
+				}
+				stopped = append(stopped, inst)
+			}
+		} else {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("new config, starting instance(s)")
+		}
+
+		instances = append(instances, newInstances...)
+		created = append(created, newInstances...)
+	}
+
+	for cfgFile, existing := range current {
+		if seen[cfgFile] {
+			continue
+		}
+		svc.logger.Info().Str("config_file", cfgFile).Msg("config removed, stopping instance(s)")
+		stopped = append(stopped, existing...)
+	}
+
+	for _, inst := range stopped {
+		inst.Stop()
+	}
+
+	svc.Lock()
+	svc.instances = instances
+	if svc.started {
+		for _, inst := range created {
+			svc.group.Go(inst.Start)
+		}
+	}
+	svc.Unlock()
+
+	return nil
+}
+
+// unchanged returns true if all of the instances created from a config file
+// were created from the version identified by cfgHash.
+func unchanged(instances []*Instance, cfgHash string) bool {
+	for _, inst := range instances {
+		if inst.cfgHash != cfgHash {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the current state of each AWS region instance.
+func (svc *AWSService) Status() []services.InstanceStatus {
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	status := make([]services.InstanceStatus, 0, len(instances))
+	for _, inst := range instances {
+		status = append(status, inst.Status())
+	}
+
+	return status
+}
+
+// CollectOnce runs a single collection for each AWS region instance, concurrently,
+// for the time range tr, waits for them to complete and returns the outcome
+// of each.
+func (svc *AWSService) CollectOnce(tr services.TimeRange) []services.CollectionResult {
+	if !svc.enabled {
+		return nil
+	}
+
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	results := make([]services.CollectionResult, len(instances))
+	var wg sync.WaitGroup
+	for idx, inst := range instances {
+		wg.Add(1)
+		go func(idx int, inst *Instance) {
+			defer wg.Done()
+			results[idx] = inst.CollectOnce(tr)
+		}(idx, inst)
+	}
+	wg.Wait()
+
+	return results
 }
 
 // Start begins collecting metrics from AWS service.
@@ -111,16 +300,26 @@ func (svc *AWSService) Start() error {
 	svc.logger.Info().Msg("AWS client starting")
 
 	// start the aws service instance(s)
+	svc.Lock()
 	for _, instance := range svc.instances {
 		inst := instance
 		svc.group.Go(inst.Start)
 	}
+	svc.started = true
+	svc.Unlock()
 
-	go func() {
-		if err := svc.group.Wait(); err != nil {
-			svc.logger.Warn().Err(err).Msg("waiting for service group")
-		}
-	}()
+	if svc.watch {
+		svc.group.Go(func() error {
+			if err := services.WatchConfigDir(svc.groupCtx, svc.confDir, svc.Scan, svc.logger); err != nil {
+				svc.logger.Error().Err(err).Msg("config changes will require a SIGHUP to be applied")
+			}
+			return nil
+		})
+	}
+
+	// instances may be added/removed by Scan, wait for the service
+	// to be stopped rather than for the current set of instances
+	<-svc.groupCtx.Done()
 
 	return svc.group.Wait()
 }
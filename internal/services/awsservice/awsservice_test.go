@@ -5,8 +5,106 @@
 
 package awsservice
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
 
 func Test(t *testing.T) {
 	t.Log("Placeholder...no test have been created")
 }
+
+func TestScan(t *testing.T) {
+	t.Log("Testing Scan")
+
+	// dry run, checks are created without the circonus api
+	dir := t.TempDir()
+	viper.Set(config.KeyDryRun, true)
+	viper.Set(config.KeyDryRunOutput, filepath.Join(dir, "dry-run.out"))
+	defer viper.Set(config.KeyDryRun, false)
+	defer viper.Set(config.KeyDryRunOutput, "")
+
+	confDir := filepath.Join(dir, "aws.d")
+	if err := os.Mkdir(confDir, 0o700); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	cfgFile := filepath.Join(confDir, "test.json")
+	writeConfig := func(t *testing.T, cfg string) {
+		t.Helper()
+		if err := os.WriteFile(cfgFile, []byte(cfg), 0o600); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(context.Background())
+	svc := &AWSService{
+		enabled:  true,
+		confDir:  confDir,
+		group:    g,
+		groupCtx: gctx,
+		logger:   zerolog.Nop(),
+	}
+	regions := func() map[string]*Instance {
+		m := make(map[string]*Instance)
+		for _, inst := range svc.instances {
+			m[inst.regionCfg.Name] = inst
+		}
+		return m
+	}
+	stopped := func(inst *Instance) bool {
+		return inst.ctx.Err() != nil
+	}
+
+	t.Log("\tnew config")
+	writeConfig(t, `{"id":"test","regions":[
+		{"name":"us-east-1","services":[{"namespace":"AWS/EC2"}]},
+		{"name":"us-west-2","services":[{"namespace":"AWS/EC2"}]}]}`)
+	if err := svc.Scan(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	initial := regions()
+	if len(initial) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(initial))
+	}
+
+	t.Log("\tchanged config, region fails to initialize")
+	writeConfig(t, `{"id":"test","period":"detailed","regions":[
+		{"name":"us-east-1","services":[{"namespace":"AWS/EC2"}]},
+		{"name":"us-west-2","services":[]}]}`)
+	if err := svc.Scan(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	current := regions()
+	if len(current) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(current))
+	}
+	if current["us-east-1"] == initial["us-east-1"] || !stopped(initial["us-east-1"]) {
+		t.Fatal("expected us-east-1 restarted")
+	}
+	if current["us-west-2"] != initial["us-west-2"] || stopped(initial["us-west-2"]) {
+		t.Fatal("expected existing us-west-2 instance kept")
+	}
+
+	t.Log("\tremoved config")
+	if err := os.Remove(cfgFile); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := svc.Scan(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if len(svc.instances) != 0 {
+		t.Fatalf("expected 0 instances, got %d", len(svc.instances))
+	}
+	for _, inst := range current {
+		if !stopped(inst) {
+			t.Fatal("expected instances stopped")
+		}
+	}
+}
@@ -22,13 +22,13 @@ type ApplicationELB struct {
 	common
 }
 
-func newApplicationELB(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newApplicationELB(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	if len(cfg.Dimensions) == 0 {
 		return nil, errors.New("metrics *require* dimension(s)")
 	}
 	ns := "AWS/ApplicationELB"
 	c := &ApplicationELB{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
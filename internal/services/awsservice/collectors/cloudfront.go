@@ -22,10 +22,10 @@ type CloudFront struct {
 	common
 }
 
-func newCloudFront(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newCloudFront(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/CloudFront"
 	c := &CloudFront{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
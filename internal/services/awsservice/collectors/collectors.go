@@ -6,17 +6,18 @@
 package collectors
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/rs/zerolog"
 )
 
@@ -35,6 +36,7 @@ type Collector interface {
 	Collect(sess *session.Session, timespan MetricTimespan, baseTags circonus.Tags) error
 	ID() string
 	DefaultMetrics() []Metric
+	Status() services.CollectorStatus
 }
 
 // AWSCollector defines a generic aws service metric collector.
@@ -86,7 +88,7 @@ type MetricTimespan struct {
 }
 
 // New creates a new collector instance.
-func New(ctx context.Context, check *circonus.Check, cfgs []AWSCollector, logger zerolog.Logger) ([]Collector, error) {
+func New(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfgs []AWSCollector, logger zerolog.Logger) ([]Collector, error) {
 	// TBD: zone/service discovery (so that bare minimum config required would be credentials)
 	//
 	// aws cloudwatch call(s) for what services are in use (if no list all active services, call each for list metrics and use ones that return >0 metrics)
@@ -108,7 +110,7 @@ func New(ctx context.Context, check *circonus.Check, cfgs []AWSCollector, logger
 		var err error
 
 		if initfn, known := cl[strings.ToLower(cfg.Namespace)]; known {
-			c, err = initfn(ctx, check, &cfg, logger)
+			c, err = initfn(ctx, check, sink, &cfg, logger)
 		} else {
 			err = errors.New("unrecognized aws service namespace")
 		}
@@ -133,7 +135,7 @@ func New(ctx context.Context, check *circonus.Check, cfgs []AWSCollector, logger
 	return cc, nil
 }
 
-type collectorInitFn func(context.Context, *circonus.Check, *AWSCollector, zerolog.Logger) (Collector, error)
+type collectorInitFn func(context.Context, *circonus.Check, circonus.Sink, *AWSCollector, zerolog.Logger) (Collector, error)
 type collectorInitList map[string]collectorInitFn
 
 func collectorList() collectorInitList {
@@ -257,7 +259,9 @@ func ConfigExample() ([]AWSCollector, error) {
 type common struct {
 	disableTime  time.Time
 	ctx          context.Context
+	stateMu      *sync.Mutex // protects enabled, disableCause, disableTime (status may be requested during collection)
 	check        *circonus.Check
+	sink         circonus.Sink
 	id           string
 	disableCause string
 	metrics      []Metric
@@ -268,7 +272,7 @@ type common struct {
 	enabled      bool
 }
 
-func newCommon(ctx context.Context, ns string, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) common {
+func newCommon(ctx context.Context, ns string, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) common {
 	var dims []*cloudwatch.Dimension
 	if len(cfg.Dimensions) > 0 {
 		dims = make([]*cloudwatch.Dimension, 0, len(cfg.Dimensions))
@@ -281,8 +285,10 @@ func newCommon(ctx context.Context, ns string, check *circonus.Check, cfg *AWSCo
 	return common{
 		id:         ns,
 		enabled:    true,
+		stateMu:    &sync.Mutex{},
 		ctx:        ctx,
 		check:      check,
+		sink:       sink,
 		dimensions: dims,
 		metrics:    cfg.Metrics,
 		tags:       cfg.Tags,
@@ -308,12 +314,12 @@ func (c *common) Collect(sess *session.Session, timespan MetricTimespan, baseTag
 		collectorFn = c.metricData
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(32768)
+	buf := c.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 
 	c.logger.Debug().Str("collector", c.ID()).Msg("collecting telemetry")
 	var dims []*cloudwatch.Dimension
-	if err := collectorFn(&buf, sess, timespan, dims, baseTags); err != nil {
+	if err := collectorFn(buf, sess, timespan, dims, baseTags); err != nil {
 		return fmt.Errorf("collecting telemetry: %w", err)
 	}
 
@@ -323,7 +329,7 @@ func (c *common) Collect(sess *session.Session, timespan MetricTimespan, baseTag
 	}
 
 	c.logger.Debug().Str("collector", c.ID()).Msg("submitting telemetry")
-	if err := c.check.SubmitMetrics(&buf); err != nil {
+	if err := buf.Submit(); err != nil {
 		return fmt.Errorf("submitting telemetry: %w", err)
 	}
 
@@ -335,6 +341,9 @@ func (c *common) Collect(sess *session.Session, timespan MetricTimespan, baseTag
 // and the collector will be re-enabled to try again after an hour. See the
 // AccessDenied case in trackAWSErrors method.
 func (c *common) Enabled() bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
 	if c.enabled {
 		return c.enabled
 	}
@@ -354,6 +363,24 @@ func (c *common) Enabled() bool {
 	return false
 }
 
+// Status returns the current state of the collector.
+func (c *common) Status() services.CollectorStatus {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	status := services.CollectorStatus{
+		ID:           c.id,
+		Enabled:      c.enabled,
+		DisableCause: c.disableCause,
+	}
+	if !c.enabled && !c.disableTime.IsZero() {
+		dt := c.disableTime
+		status.DisableTime = &dt
+	}
+
+	return status
+}
+
 // ID returns the collector's string id/name (e.g. used in logging by the instance using the collector).
 func (c *common) ID() string {
 	return c.id
@@ -369,6 +396,28 @@ func (c *common) done() bool {
 	}
 }
 
+// recordAPICall tracks a call to an aws api, and the aws error code if
+// the call failed, in the check's self-telemetry.
+func (c *common) recordAPICall(err error) {
+	if c.check == nil {
+		return
+	}
+	c.check.RecordAPICall(c.id, awsErrorCode(err))
+}
+
+// awsErrorCode returns the aws error code for err, "unknown" if err is not
+// an aws error, or an empty string if there was no error.
+func awsErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return "unknown"
+}
+
 // trackAWSErrors will unravel errors and log the specific aws errors. it will return
 // the specific error if it is an aws error otherwise it returns the original error
 // or nil if there was no error passed.
@@ -387,6 +436,8 @@ func (c *common) trackAWSErrors(err error) error {
 				Str("req_id", reqErr.RequestID()).
 				Msg("aws request error")
 			if reqErr.Code() == "AccessDenied" { // AccessDenied to resource
+				c.stateMu.Lock()
+				defer c.stateMu.Unlock()
 				c.enabled = false
 				c.disableTime = time.Now()
 				c.disableCause = reqErr.Message()
@@ -20,10 +20,10 @@ type DX struct {
 	common
 }
 
-func newDX(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newDX(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/DX"
 	c := &DX{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
@@ -23,13 +23,13 @@ type DynamoDB struct {
 	common
 }
 
-func newDynamoDB(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newDynamoDB(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	if len(cfg.Dimensions) == 0 {
 		return nil, errors.New("metrics *require* dimensions")
 	}
 	ns := "AWS/DynamoDB"
 	c := &DynamoDB{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
@@ -6,7 +6,6 @@
 package collectors
 
 import (
-	"bytes"
 	"context"
 	"strings"
 
@@ -28,10 +27,10 @@ type EBS struct {
 	common
 }
 
-func newEBS(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newEBS(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/EBS"
 	c := &EBS{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
@@ -59,20 +58,19 @@ func (c *EBS) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 		collectorFn = c.metricData
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(32768)
+	buf := c.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 
 	// call once for entire zone
 	var dims []*cloudwatch.Dimension
-	if err := collectorFn(&buf, sess, timespan, dims, baseTags); err != nil {
+	if err := collectorFn(buf, sess, timespan, dims, baseTags); err != nil {
 		return errors.Wrap(err, "collecting telemetry")
 	}
 	if buf.Len() > 0 {
 		c.logger.Debug().Str("collector", c.ID()).Msg("submitting telemetry")
-		if err := c.check.SubmitMetrics(&buf); err != nil {
+		if err := buf.Submit(); err != nil {
 			c.logger.Error().Err(err).Msg("submitting telemetry")
 		}
-		buf.Reset()
 	}
 
 	// call for each volume returned from query to ec2 service
@@ -100,7 +98,7 @@ func (c *EBS) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 		if len(volumeInfo.tags) > 0 {
 			metricTags = append(metricTags, volumeInfo.tags...)
 		}
-		if err := collectorFn(&buf, sess, timespan, dims, metricTags); err != nil {
+		if err := collectorFn(buf, sess, timespan, dims, metricTags); err != nil {
 			c.logger.Error().Err(err).Msg("collecting telemetry")
 		}
 		if buf.Len() == 0 {
@@ -108,10 +106,9 @@ func (c *EBS) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 			continue
 		}
 		c.logger.Debug().Str("collector", c.ID()).Msg("submitting telemetry")
-		if err := c.check.SubmitMetrics(&buf); err != nil {
+		if err := buf.Submit(); err != nil {
 			c.logger.Error().Err(err).Msg("submitting telemetry")
 		}
-		buf.Reset()
 	}
 
 	return nil
@@ -135,6 +132,7 @@ func (c *EBS) ebsVolumes(sess client.ConfigProvider, baseTags circonus.Tags) ([]
 	ec2Svc := ec2.New(sess)
 	input := &ec2.DescribeVolumesInput{}
 	results, err := ec2Svc.DescribeVolumes(input)
+	c.recordAPICall(err)
 	if err != nil {
 		return ebsVolumes, errors.Wrap(err, "describing ebs volumes")
 	}
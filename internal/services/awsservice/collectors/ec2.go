@@ -6,7 +6,6 @@
 package collectors
 
 import (
-	"bytes"
 	"context"
 	"strings"
 
@@ -35,10 +34,10 @@ type ec2instance struct {
 	tags circonus.Tags
 }
 
-func newEC2(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newEC2(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/EC2"
 	c := &EC2{
-		common:  newCommon(ctx, ns, check, cfg, logger),
+		common:  newCommon(ctx, ns, check, sink, cfg, logger),
 		filters: cfg.InstanceFilters,
 	}
 	if len(c.metrics) == 0 {
@@ -75,8 +74,8 @@ func (c *EC2) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 	if c.useGMD {
 		collectorFn = c.metricData
 	}
-	var buf bytes.Buffer
-	buf.Grow(32768)
+	buf := c.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 	metricDimensionName := "InstanceId"
 	for _, instanceInfo := range ec2instances {
 		instanceInfo := instanceInfo
@@ -94,7 +93,7 @@ func (c *EC2) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 		if len(instanceInfo.tags) > 0 {
 			metricTags = append(metricTags, instanceInfo.tags...)
 		}
-		if err := collectorFn(&buf, sess, timespan, dims, metricTags); err != nil {
+		if err := collectorFn(buf, sess, timespan, dims, metricTags); err != nil {
 			c.logger.Error().Err(err).Msg("collecting telemetry")
 		}
 		if buf.Len() == 0 {
@@ -102,10 +101,9 @@ func (c *EC2) Collect(sess *session.Session, timespan MetricTimespan, baseTags c
 			continue
 		}
 		c.logger.Debug().Str("collector", c.ID()).Msg("submitting telemetry")
-		if err := c.check.SubmitMetrics(&buf); err != nil {
+		if err := buf.Submit(); err != nil {
 			c.logger.Error().Err(err).Msg("submitting telemetry")
 		}
-		buf.Reset()
 	}
 
 	return nil
@@ -131,6 +129,7 @@ func (c *EC2) ec2Instances(sess client.ConfigProvider, baseTags circonus.Tags) (
 		describeInstancesInput = &ec2.DescribeInstancesInput{Filters: filters}
 	}
 	results, err := ec2Svc.DescribeInstances(describeInstancesInput)
+	c.recordAPICall(err)
 	if err != nil {
 		return ec2List, errors.Wrap(err, "describing instances")
 	}
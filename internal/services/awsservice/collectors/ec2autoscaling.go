@@ -20,10 +20,10 @@ type EC2AutoScaling struct {
 	common
 }
 
-func newEC2AutoScaling(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newEC2AutoScaling(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/EC2AutoScaling"
 	c := &EC2AutoScaling{
-		common: newCommon(ctx, ns, check, cfg, logger),
+		common: newCommon(ctx, ns, check, sink, cfg, logger),
 	}
 	if len(c.metrics) == 0 {
 		c.metrics = c.DefaultMetrics()
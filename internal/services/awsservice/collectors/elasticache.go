@@ -6,7 +6,6 @@
 package collectors
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 
@@ -31,10 +30,10 @@ type ElastiCache struct {
 }
 
 // newElastiCache creates a new ElastiCache telemetry collector.
-func newElastiCache(ctx context.Context, check *circonus.Check, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
+func newElastiCache(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *AWSCollector, logger zerolog.Logger) (Collector, error) {
 	ns := "AWS/ElastiCache"
 	c := &ElastiCache{
-		common:     newCommon(ctx, ns, check, cfg, logger),
+		common:     newCommon(ctx, ns, check, sink, cfg, logger),
 		clusterIDs: cfg.CacheClusterIDs,
 	}
 	if len(c.metrics) == 0 {
@@ -68,8 +67,8 @@ func (c *ElastiCache) Collect(sess *session.Session, timespan MetricTimespan, ba
 	if c.useGMD {
 		collectorFn = c.metricData
 	}
-	var buf bytes.Buffer
-	buf.Grow(32768)
+	buf := c.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 
 	for cid, nodes := range clusterList {
 		// GetMetricData and GetMetricStatistics both have their pros and cons...
@@ -86,7 +85,7 @@ func (c *ElastiCache) Collect(sess *session.Session, timespan MetricTimespan, ba
 					Value: aws.String(nid),
 				},
 			}
-			if err := collectorFn(&buf, sess, timespan, dimensions, baseTags); err != nil {
+			if err := collectorFn(buf, sess, timespan, dimensions, baseTags); err != nil {
 				c.logger.Warn().Err(err).Str("cluster_id", cid).Str("node_id", nid).Msg("fetching telemetry")
 				continue
 			}
@@ -95,10 +94,9 @@ func (c *ElastiCache) Collect(sess *session.Session, timespan MetricTimespan, ba
 				continue
 			}
 			c.logger.Debug().Str("collector", c.ID()).Msg("submitting telemetry")
-			if err := c.check.SubmitMetrics(&buf); err != nil {
+			if err := buf.Submit(); err != nil {
 				c.logger.Warn().Err(err).Str("cluster_id", cid).Str("node_id", nid).Msg("submitting telemetry")
 			}
-			buf.Reset()
 		}
 	}
 
@@ -121,6 +119,7 @@ func (c *ElastiCache) clusterList(sess client.ConfigProvider) (map[string][]stri
 				ShowCacheNodeInfo: aws.Bool(true),
 			}
 			cl, err := ecSvc.DescribeCacheClusters(dcci)
+			c.recordAPICall(err)
 			if err != nil {
 				var awsErr awserr.Error
 				if errors.As(err, &awsErr) {
@@ -148,6 +147,7 @@ func (c *ElastiCache) clusterList(sess client.ConfigProvider) (map[string][]stri
 		ShowCacheNodeInfo: aws.Bool(true),
 	}
 	cl, err := ecSvc.DescribeCacheClusters(dcci)
+	c.recordAPICall(err)
 	if err != nil {
 		return nil, fmt.Errorf("describing elasticache clusters: %w", err)
 	}
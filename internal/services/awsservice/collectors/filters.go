@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collectors
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+// MetricFilters returns check bundle metric filters generated from the
+// collector configurations: one allowing each enabled metric, with its
+// configured stats, of each enabled collector (the collector's default
+// metrics if none are configured), followed by one denying all other metrics.
+func MetricFilters(cfgs []AWSCollector) []circonus.MetricFilter {
+	var filters []circonus.MetricFilter
+	for _, cfg := range cfgs {
+		if cfg.Disabled {
+			continue
+		}
+		for _, m := range collectorMetrics(cfg) {
+			if m.AWSMetric.Disabled {
+				continue
+			}
+			filters = append(filters, circonus.MetricFilter{
+				Type:    circonus.MetricFilterAllow,
+				Filter:  metricFilter(m),
+				Comment: "generated " + cfg.Namespace,
+			})
+		}
+	}
+	return append(filters, circonus.MetricFilter{
+		Type:    circonus.MetricFilterDeny,
+		Filter:  "^.+$",
+		Comment: "generated, other metrics",
+	})
+}
+
+// collectorMetrics returns the metrics a collector uses, the configured
+// metrics or, if none are configured, the collector's default metrics.
+func collectorMetrics(cfg AWSCollector) []Metric {
+	if len(cfg.Metrics) > 0 {
+		return cfg.Metrics
+	}
+	initfn, known := collectorList()[strings.ToLower(cfg.Namespace)]
+	if !known {
+		return nil
+	}
+	c, err := initfn(context.Background(), nil, nil, &cfg, zerolog.Nop())
+	if err != nil || c == nil {
+		return nil // collector is skipped, no metrics
+	}
+	return c.DefaultMetrics()
+}
+
+// metricFilter returns a regex matching the names given to the samples of
+// metric (see recordMetric), e.g. ^CPUUtilization`(?:Average|Maximum)(?:\|ST\[.*\])?$
+func metricFilter(m Metric) string {
+	name := m.CirconusMetric.Name
+	if name == "" {
+		name = m.AWSMetric.Name
+	}
+	filter := "^" + regexp.QuoteMeta(name)
+	if len(m.AWSMetric.Stats) > 0 {
+		stats := make([]string, len(m.AWSMetric.Stats))
+		for i, stat := range m.AWSMetric.Stats {
+			stats[i] = regexp.QuoteMeta(stat)
+		}
+		filter += "`(?:" + strings.Join(stats, "|") + ")"
+	}
+	return filter + `(?:\|ST\[.*\])?$`
+}
@@ -0,0 +1,72 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collectors
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMetricFilters(t *testing.T) {
+	t.Log("Testing MetricFilters")
+
+	cfgs := []AWSCollector{
+		{Namespace: "AWS/EC2", Metrics: []Metric{
+			{AWSMetric: AWSMetric{Name: "CPUUtilization", Stats: []string{"Average", "Maximum"}}},
+			{AWSMetric: AWSMetric{Name: "NetworkIn", Stats: []string{"Sum"}, Disabled: true}},
+			{CirconusMetric: CirconusMetric{Name: "disk.read"}, AWSMetric: AWSMetric{Name: "DiskReadOps", Stats: []string{"Sum"}}},
+		}},
+		{Namespace: "AWS/EBS", Disabled: true, Metrics: []Metric{
+			{AWSMetric: AWSMetric{Name: "VolumeReadOps", Stats: []string{"Sum"}}},
+		}},
+	}
+
+	filters := MetricFilters(cfgs)
+	if len(filters) != 3 {
+		t.Fatalf("expected 3 filters, got %+v", filters)
+	}
+	if filters[2].Type != "deny" {
+		t.Fatalf("expected last filter to deny other metrics, got %+v", filters[2])
+	}
+
+	allowed := func(name string) bool {
+		for _, f := range filters[:2] {
+			if regexp.MustCompile(f.Filter).MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, name := range []string{"CPUUtilization`Average", "CPUUtilization`Maximum|ST[aws_region:us-east-1]", "disk.read`Sum"} {
+		if !allowed(name) {
+			t.Fatalf("expected (%s) allowed", name)
+		}
+	}
+	for _, name := range []string{"CPUUtilization`Minimum", "NetworkIn`Sum", "DiskReadOps`Sum", "VolumeReadOps`Sum", "diskXread`Sum"} {
+		if allowed(name) {
+			t.Fatalf("expected (%s) not allowed", name)
+		}
+	}
+}
+
+func TestMetricFiltersDefaultMetrics(t *testing.T) {
+	t.Log("Testing MetricFilters, collector without metrics")
+
+	cfgs := []AWSCollector{{Namespace: "AWS/EC2"}}
+
+	filters := MetricFilters(cfgs)
+	ec2 := &EC2{}
+	if len(filters) != len(ec2.DefaultMetrics())+1 {
+		t.Fatalf("expected %d filters, got %+v", len(ec2.DefaultMetrics())+1, filters)
+	}
+
+	m := ec2.DefaultMetrics()[0]
+	name := m.AWSMetric.Name + "`" + m.AWSMetric.Stats[0]
+	if !regexp.MustCompile(filters[0].Filter).MatchString(name) {
+		t.Fatalf("expected (%s) allowed by %s", name, filters[0].Filter)
+	}
+}
@@ -7,7 +7,6 @@ package collectors
 
 import (
 	"fmt"
-	"io"
 	"sort"
 	"strings"
 	"time"
@@ -22,7 +21,7 @@ import (
 //   NOTE: override in specific service if needed (see ec2)
 
 // nolint: gocyclo
-func (c *common) metricData(metricDest io.Writer, sess client.ConfigProvider, timespan MetricTimespan, dimensions []*cloudwatch.Dimension, baseTags circonus.Tags) error {
+func (c *common) metricData(metricDest circonus.Batch, sess client.ConfigProvider, timespan MetricTimespan, dimensions []*cloudwatch.Dimension, baseTags circonus.Tags) error {
 	if metricDest == nil {
 		return errors.New("invalid metric destination (nil)")
 	}
@@ -87,6 +86,7 @@ func (c *common) metricData(metricDest io.Writer, sess client.ConfigProvider, ti
 			MetricDataQueries: metricDataQueries,
 		}
 		results, err := cwSvc.GetMetricData(&getMetricDataInput)
+		c.recordAPICall(err)
 		if err != nil {
 			c.logger.Error().Err(err).Msg("retrieving metric data")
 			continue
@@ -152,6 +152,7 @@ func (c *common) metricData(metricDest io.Writer, sess client.ConfigProvider, ti
 			}
 			getMetricDataInput.SetNextToken(*results.NextToken)
 			results, err = cwSvc.GetMetricData(&getMetricDataInput)
+			c.recordAPICall(err)
 			if err != nil {
 				c.logger.Error().Err(err).Msg("retrieving metric data w/NextToken")
 				break
@@ -184,7 +185,7 @@ func (c *common) sortMetricDataSamples(ts *cloudwatch.MetricDataResult) []mdSamp
 	return samples
 }
 
-func (c *common) metricStats(metricDest io.Writer, sess client.ConfigProvider, timespan MetricTimespan, dimensions []*cloudwatch.Dimension, baseTags circonus.Tags) error {
+func (c *common) metricStats(metricDest circonus.Batch, sess client.ConfigProvider, timespan MetricTimespan, dimensions []*cloudwatch.Dimension, baseTags circonus.Tags) error {
 	if metricDest == nil {
 		return errors.New("invalid metric destination (nil)")
 	}
@@ -222,6 +223,7 @@ func (c *common) metricStats(metricDest io.Writer, sess client.ConfigProvider, t
 		c.logger.Debug().Interface("inputs", getMetricStatisticsInput).Msg("metric stats inputs")
 
 		result, err := cwSvc.GetMetricStatistics(&getMetricStatisticsInput)
+		c.recordAPICall(err)
 		if err != nil {
 			c.logger.Error().Err(err).Str("aws_metric_name", metricDefinition.AWSMetric.Name).Msg("retrieving metric statistics")
 			continue
@@ -308,7 +310,7 @@ func (c *common) sortMetricStatDatapoints(datapoints []*cloudwatch.Datapoint, md
 }
 
 // recordMetric creates a metric name w/encoded stream tags then writes the metric sample to the metric destination.
-func (c *common) recordMetric(metricDest io.Writer, metric Metric, metricStat string, val interface{}, ts *time.Time, baseTags circonus.Tags) error {
+func (c *common) recordMetric(metricDest circonus.Batch, metric Metric, metricStat string, val interface{}, ts *time.Time, baseTags circonus.Tags) error {
 	mn := metric.CirconusMetric.Name
 	if mn == "" {
 		mn = metric.AWSMetric.Name
@@ -339,16 +341,20 @@ func (c *common) recordMetric(metricDest io.Writer, metric Metric, metricStat st
 			c.logger.Debug().Str("encoded_metric_name", metricName).Int64("epoch", ts.Unix()).Msg("for data api call")
 			c.logger.Debug().Str("metric", mn).Strs("tags", mt).Str("type", "n").Float64("val", val.(float64)).Time("ts", *ts).Msg("metric to circonus")
 		}
-		err = c.check.WriteMetricSample(metricDest, metricName, "n", val.(float64), ts)
+		if metric.CirconusMetric.Type == "counter" {
+			err = circonus.WriteCounterSample(metricDest, metricName, val.(float64), ts)
+			break
+		}
+		err = metricDest.WriteMetricSample(metricName, "n", val.(float64), ts)
 	case "histogram":
 		if strings.Contains(metricName, "CPUUtilization") {
 			mt := c.check.EncodeMetricTags(tags)
 			c.logger.Debug().Str("encoded_metric_name", metricName).Int64("epoch", ts.Unix()).Msg("for data api call")
 			c.logger.Debug().Str("metric", mn).Strs("tags", mt).Str("type", "h").Float64("val", val.(float64)).Time("ts", *ts).Msg("metric to circonus")
 		}
-		err = c.check.WriteMetricSample(metricDest, metricName, "h", val.(float64), ts)
+		err = metricDest.WriteMetricSample(metricName, "h", val.(float64), ts)
 	case "text":
-		err = c.check.WriteMetricSample(metricDest, metricName, "s", fmt.Sprintf("%v", val), ts)
+		err = metricDest.WriteMetricSample(metricName, "s", fmt.Sprintf("%v", val), ts)
 	default:
 		c.logger.Warn().Interface("metric", metric).Msg("invalid Circonus Metric Type configured, ignoring metric sample")
 	}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collectors
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+)
+
+var (
+	// validStats are the statistics the collectors request (see sortMetricStatDatapoints).
+	validStats = map[string]bool{
+		"Average":     true,
+		"Sum":         true,
+		"Minimum":     true,
+		"Maximum":     true,
+		"SampleCount": true,
+	}
+	// percentileStatRx matches percentile statistics (e.g. p99, p99.9), only
+	// available with GetMetricData (use_gmd).
+	percentileStatRx = regexp.MustCompile(`^p(100|[0-9]{1,2})(\.[0-9]{1,2})?$`)
+	// validMetricTypes are the supported CirconusMetric.Type values.
+	validMetricTypes = map[string]bool{
+		"gauge":     true,
+		"counter":   true,
+		"histogram": true,
+		"text":      true,
+	}
+)
+
+// Validate returns the problems found with the collector configurations,
+// field paths are relative to the list (e.g. [0].metrics[1].aws.stats[0]).
+func Validate(cfgs []AWSCollector) []error {
+	var errs []error
+	cl := collectorList()
+	for i, cfg := range cfgs {
+		field := fmt.Sprintf("[%d]", i)
+		switch {
+		case cfg.Namespace == "":
+			errs = append(errs, config.FieldErrorf(field+".namespace", "required"))
+		default:
+			if _, known := cl[strings.ToLower(cfg.Namespace)]; !known {
+				errs = append(errs, config.FieldErrorf(field+".namespace", "unrecognized aws service namespace (%s)", cfg.Namespace))
+			}
+		}
+		for j, m := range cfg.Metrics {
+			errs = append(errs, validateMetric(fmt.Sprintf("%s.metrics[%d]", field, j), m, cfg.UseGMD)...)
+		}
+	}
+	return errs
+}
+
+func validateMetric(field string, m Metric, useGMD bool) []error {
+	var errs []error
+
+	if m.AWSMetric.Name == "" {
+		errs = append(errs, config.FieldErrorf(field+".aws.name", "required"))
+	}
+	if m.AWSMetric.Units == "" {
+		errs = append(errs, config.FieldErrorf(field+".aws.units", "required"))
+	}
+	if len(m.AWSMetric.Stats) == 0 {
+		errs = append(errs, config.FieldErrorf(field+".aws.stats", "required"))
+	}
+	for k, stat := range m.AWSMetric.Stats {
+		if validStats[stat] {
+			continue
+		}
+		if percentileStatRx.MatchString(stat) {
+			if !useGMD {
+				errs = append(errs, config.FieldErrorf(fmt.Sprintf("%s.aws.stats[%d]", field, k), "percentile statistic (%s) requires use_gmd", stat))
+			}
+			continue
+		}
+		errs = append(errs, config.FieldErrorf(fmt.Sprintf("%s.aws.stats[%d]", field, k), "invalid statistic (%s), expected Average, Sum, Minimum, Maximum, SampleCount, or a percentile (e.g. p99) with use_gmd", stat))
+	}
+
+	switch {
+	case m.CirconusMetric.Type == "":
+		errs = append(errs, config.FieldErrorf(field+".circonus.type", "required"))
+	case !validMetricTypes[m.CirconusMetric.Type]:
+		errs = append(errs, config.FieldErrorf(field+".circonus.type", "invalid metric type (%s), expected gauge, counter, histogram, or text", m.CirconusMetric.Type))
+	}
+
+	return errs
+}
@@ -0,0 +1,53 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collectors
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	t.Log("Testing Validate")
+
+	metric := func(stat, typ string) Metric {
+		return Metric{
+			CirconusMetric: CirconusMetric{Type: typ},
+			AWSMetric:      AWSMetric{Name: "CPUUtilization", Units: "Percent", Stats: []string{stat}},
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		cfgs := []AWSCollector{
+			{Namespace: "AWS/EC2", Metrics: []Metric{metric("Average", "gauge")}},
+			{Namespace: "AWS/EBS", UseGMD: true, Metrics: []Metric{metric("p99.9", "histogram")}},
+		}
+		if errs := Validate(cfgs); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		cfgs := []AWSCollector{
+			{Namespace: "AWS/Nope"},
+			{Namespace: "AWS/EC2", Metrics: []Metric{metric("Avg", "gauge"), metric("p99", "gauge2")}},
+		}
+		expect := []string{
+			"[0].namespace: unrecognized aws service namespace (AWS/Nope)",
+			"[1].metrics[0].aws.stats[0]: invalid statistic (Avg), expected Average, Sum, Minimum, Maximum, SampleCount, or a percentile (e.g. p99) with use_gmd",
+			"[1].metrics[1].aws.stats[0]: percentile statistic (p99) requires use_gmd",
+			"[1].metrics[1].circonus.type: invalid metric type (gauge2), expected gauge, counter, histogram, or text",
+		}
+		errs := Validate(cfgs)
+		if len(errs) != len(expect) {
+			t.Fatalf("expected %d errors, got %v", len(expect), errs)
+		}
+		for i, e := range expect {
+			if errs[i].Error() != e {
+				t.Fatalf("expected (%s), got (%s)", e, errs[i])
+			}
+		}
+	}
+}
@@ -10,17 +10,20 @@ package awsservice
 import (
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice/collectors"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/sinks"
 )
 
 // Config defines an AWS service instance configuration
 // NOTE: warning - ID must be thought of as immutable - if it changes a new check will be created.
 type Config struct {
-	ID       string                 `json:"id" toml:"id" yaml:"id"`                   // unique id for this service client instance, no spaces (ties several things together, short and immutable - logging, check search/create, tags, etc.)
-	Regions  []AWSRegion            `json:"regions" toml:"regions" yaml:"regions"`    // list of region specific configurations
-	AWS      AWS                    `json:"aws" toml:"aws" yaml:"aws"`                // REQUIRED, aws credentials
-	Circonus circonus.ServiceConfig `json:"circonus" toml:"circonus" yaml:"circonus"` // REQUIRED, circonus config: api credentials, check, broker, etc.
-	Period   string                 `json:"period" toml:"period" yaml:"period"`       // 'basic' or 'detailed'
-	Tags     circonus.Tags          `json:"tags" toml:"tags" yaml:"tags"`             // global tags, added to all metrics
+	ID                    string                 `json:"id" toml:"id" yaml:"id"`                                                                // unique id for this service client instance, no spaces (ties several things together, short and immutable - logging, check search/create, tags, etc.)
+	Regions               []AWSRegion            `json:"regions" toml:"regions" yaml:"regions"`                                                 // list of region specific configurations
+	AWS                   AWS                    `json:"aws" toml:"aws" yaml:"aws"`                                                             // REQUIRED, aws credentials
+	Circonus              circonus.ServiceConfig `json:"circonus" toml:"circonus" yaml:"circonus"`                                              // REQUIRED, circonus config: api credentials, check, broker, etc.
+	Period                string                 `json:"period" toml:"period" yaml:"period"`                                                    // 'basic' or 'detailed'
+	Tags                  circonus.Tags          `json:"tags" toml:"tags" yaml:"tags"`                                                          // global tags, added to all metrics
+	Sinks                 []sinks.Config         `json:"sinks" toml:"sinks" yaml:"sinks"`                                                       // additional destinations for metric samples (file, stdout, http)
+	GenerateMetricFilters bool                   `json:"generate_metric_filters" toml:"generate_metric_filters" yaml:"generate_metric_filters"` // DEFAULT false - check bundle metric filters allow only the enabled metrics of each region
 }
 
 // AWSRegion defines a specific aws region from which to collect metrics.
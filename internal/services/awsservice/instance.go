@@ -8,8 +8,6 @@ package awsservice
 import (
 	"context"
 	"fmt"
-	"os"
-	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -21,6 +19,7 @@ import (
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice/collectors"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -30,16 +29,22 @@ import (
 // Note: a Instance has a 1:1 relation with aws:circ - each Instance has (or, may have)
 // a different set of aws and/or circonus credentials.
 type Instance struct {
-	ctx        context.Context
-	cfg        *Config
-	regionCfg  *AWSRegion
-	check      *circonus.Check
-	lastStart  *time.Time
-	collectors []collectors.Collector
-	baseTags   circonus.Tags
-	logger     zerolog.Logger
-	interval   uint
-	period     int64
+	ctx          context.Context
+	cancel       context.CancelFunc
+	cfg          *Config
+	cfgFile      string
+	cfgHash      string
+	regionCfg    *AWSRegion
+	check        *circonus.Check
+	sink         circonus.Sink
+	lastStart    *time.Time
+	lastDuration time.Duration
+	collectors   []collectors.Collector
+	baseTags     circonus.Tags
+	watermarks   *services.Watermarks
+	logger       zerolog.Logger
+	interval     uint
+	period       int64
 	sync.Mutex
 	running bool
 }
@@ -51,106 +56,138 @@ func (svc *AWSService) initInstances(confDir string) error {
 		return errors.New("invalid config dir (empty)")
 	}
 
-	entries, err := os.ReadDir(confDir)
+	files, err := services.ConfigFiles(confDir)
 	if err != nil {
 		return errors.Wrap(err, "reading AWS config dir")
 	}
 
-	for _, entry := range entries {
-		entry := entry
-		if entry.IsDir() {
-			continue
-		}
-		if !strings.Contains(".json|.toml|.yaml", filepath.Ext(entry.Name())) { //nolint:gocritic
+	for _, cf := range files {
+		instances, _, err := svc.instancesFromConfig(cf.Path, cf.Hash)
+		if err != nil {
+			svc.logger.Error().Err(err).Str("file", filepath.Base(cf.Path)).Msg("loading config, skipping")
 			continue
 		}
+		svc.instances = append(svc.instances, instances...)
+	}
 
-		var cfg Config
-		if err := config.LoadConfigFile(path.Join(confDir, entry.Name()), &cfg); err != nil {
-			svc.logger.Error().Err(err).Str("file", entry.Name()).Msg("loading config, skipping")
-			continue
-		}
+	if len(svc.instances) == 0 {
+		return errors.New("no valid AWS configs found")
+	}
 
-		if cfg.ID == "" {
-			svc.logger.Error().Str("file", entry.Name()).Msg("invalid config ID (empty), skipping")
-			continue
-		}
-		if strings.Contains(cfg.ID, " ") {
-			svc.logger.Error().Str("file", entry.Name()).Msg("invalid config ID (contains spaces), skipping")
-			continue
-		}
-		if len(cfg.Regions) == 0 {
-			svc.logger.Error().Str("file", entry.Name()).Msg("invalid config regions (empty), skipping")
-		}
+	return nil
+}
+
+// instancesFromConfig creates a region Instance for each region defined in
+// the configuration file, and returns the regions which failed to initialize.
+// cfgHash identifies the version of the configuration file the instances were
+// created from.
+func (svc *AWSService) instancesFromConfig(cfgFile, cfgHash string) ([]*Instance, map[string]bool, error) {
+	var cfg Config
+	if err := config.LoadConfigFile(cfgFile, &cfg); err != nil {
+		return nil, nil, errors.Wrap(err, "loading config file")
+	}
+
+	if err := services.ValidateID(cfg.ID); err != nil {
+		return nil, nil, err
+	}
+	if len(cfg.Regions) == 0 {
+		return nil, nil, errors.New("invalid config regions (empty)")
+	}
 
-		// based on cfg.Period - collect every 1min for 'detailed' or every 5min for 'basic'
-		period := 300
-		if cfg.Period == "detailed" {
-			period = 60
+	// based on cfg.Period - collect every 1min for 'detailed' or every 5min for 'basic'
+	period := 300
+	if cfg.Period == "detailed" {
+		period = 60
+	}
+	// used to control how many samples we request - calculating start from
+	// time.Now (e.g. time.Now().Add(- (interval * time.Second))). desired
+	// number of samples is three. if exactly three * period is used,
+	// cloudwatch sdk will often respond with only the last two samples.
+	// so use 3 * period, plus a little extra cushion.
+	// interval := (period * 3) + (period / 2)
+	// seeing gaps, ask for more repetitive data...
+	interval := period
+
+	instances := make([]*Instance, 0, len(cfg.Regions))
+	failed := make(map[string]bool)
+
+	for _, regionConfig := range cfg.Regions {
+		regionConfig := regionConfig
+		ctx, cancel := context.WithCancel(svc.groupCtx)
+		instance := &Instance{
+			cfg:       &cfg,
+			cfgFile:   cfgFile,
+			cfgHash:   cfgHash,
+			regionCfg: &regionConfig,
+			ctx:       ctx,
+			cancel:    cancel,
+			interval:  uint(interval),
+			logger:    svc.logger.With().Str("id", cfg.ID).Str("region", regionConfig.Name).Logger(),
+			period:    int64(60), // always request 60 second granularity
 		}
-		// used to control how many samples we request - calculating start from
-		// time.Now (e.g. time.Now().Add(- (interval * time.Second))). desired
-		// number of samples is three. if exactly three * period is used,
-		// cloudwatch sdk will often respond with only the last two samples.
-		// so use 3 * period, plus a little extra cushion.
-		// interval := (period * 3) + (period / 2)
-		// seeing gaps, ask for more repetitive data...
-		interval := period
-
-		for _, regionConfig := range cfg.Regions {
-			regionConfig := regionConfig
-			instance := &Instance{
-				cfg:       &cfg,
-				regionCfg: &regionConfig,
-				ctx:       svc.groupCtx,
-				interval:  uint(interval),
-				logger:    svc.logger.With().Str("id", cfg.ID).Str("region", regionConfig.Name).Logger(),
-				period:    int64(60), // always request 60 second granularity
-			}
-			instance.logger.Debug().Str("aws_region", regionConfig.Name).Msg("initialized client instance for region")
-
-			checkConfig := &circonus.Config{
-				ID:            fmt.Sprintf("aws_%s_%s", cfg.ID, regionConfig.Name),
-				DisplayName:   fmt.Sprintf("aws %s %s /%s", cfg.ID, regionConfig.Name, release.NAME),
-				CheckBundleID: cfg.Circonus.CID,
-				APIKey:        cfg.Circonus.Key,
-				APIApp:        cfg.Circonus.App,
-				APIURL:        cfg.Circonus.URL,
-				Debug:         cfg.Circonus.Debug,
-				Logger:        instance.logger,
-				Tags:          fmt.Sprintf("%s:aws,aws_region:%s", release.NAME, regionConfig.Name),
-			}
-			if len(cfg.Tags) > 0 { // if top-level tags are configured, add them to check
-				tags := make([]string, len(cfg.Tags))
-				for idx, tag := range cfg.Tags {
-					tags[idx] = tag.Category + ":" + tag.Value
-				}
-				checkConfig.Tags += "," + strings.Join(tags, ",")
-			}
+		instance.logger.Debug().Str("aws_region", regionConfig.Name).Msg("initialized client instance for region")
 
-			chk, err := circonus.NewCheck("aws", checkConfig)
+		if !svc.stateless {
+			wm, err := services.ConfiguredWatermarks()
 			if err != nil {
-				instance.logger.Error().Err(err).Msg("creating Circonus Check instance, skipping")
-				continue
+				instance.logger.Warn().Err(err).Msg("loading collection state, collections will not resume after restart")
 			}
-			instance.check = chk
+			instance.watermarks = wm
+		}
 
-			ms, err := collectors.New(instance.ctx, instance.check, regionConfig.Services, instance.logger)
-			if err != nil {
-				instance.logger.Warn().Err(err).Msg("setting up aws metric services")
-				continue
+		checkConfig := services.CheckConfig(cfg.Circonus, instance.logger)
+		checkConfig.ID = fmt.Sprintf("aws_%s_%s", cfg.ID, regionConfig.Name)
+		checkConfig.DisplayName = fmt.Sprintf("aws %s %s /%s", cfg.ID, regionConfig.Name, release.NAME)
+		checkConfig.Tags = fmt.Sprintf("%s:aws,aws_region:%s", release.NAME, regionConfig.Name)
+		if cfg.GenerateMetricFilters { // allow only the enabled metrics of the region
+			filters := append([]circonus.MetricFilter{}, cfg.Circonus.MetricFilters...)
+			checkConfig.MetricFilters = append(filters, collectors.MetricFilters(regionConfig.Services)...)
+		}
+		if len(cfg.Tags) > 0 { // if top-level tags are configured, add them to check
+			tags := make([]string, len(cfg.Tags))
+			for idx, tag := range cfg.Tags {
+				tags[idx] = tag.Category + ":" + tag.Value
 			}
-			instance.collectors = ms
+			checkConfig.Tags += "," + strings.Join(tags, ",")
+		}
 
-			svc.instances = append(svc.instances, instance)
+		chk, err := circonus.NewCheck("aws", checkConfig)
+		if err != nil {
+			instance.logger.Error().Err(err).Msg("creating Circonus Check instance, skipping")
+			cancel()
+			failed[regionConfig.Name] = true
+			continue
+		}
+		instance.check = chk
+
+		sink, err := services.NewSink(chk, cfg.Sinks, instance.logger)
+		if err != nil {
+			instance.logger.Error().Err(err).Msg("creating metric sinks, skipping")
+			cancel()
+			chk.Close()
+			failed[regionConfig.Name] = true
+			continue
 		}
+		instance.sink = sink
+
+		ms, err := collectors.New(instance.ctx, instance.check, instance.sink, regionConfig.Services, instance.logger)
+		if err != nil {
+			instance.logger.Warn().Err(err).Msg("setting up aws metric services")
+			cancel()
+			services.CloseCheck(chk, sink, instance.logger)
+			failed[regionConfig.Name] = true
+			continue
+		}
+		instance.collectors = ms
+
+		instances = append(instances, instance)
 	}
 
-	if len(svc.instances) == 0 {
-		return errors.New("no valid AWS configs found")
+	if len(instances) == 0 {
+		return nil, nil, errors.New("no valid AWS regions initialized")
 	}
 
-	return nil
+	return instances, failed, nil
 }
 
 // Start metric collections based on the configured interval - intended to be run in a goroutine (e.g. errgroup).
@@ -195,46 +232,145 @@ func (inst *Instance) Start() error {
 				continue
 			}
 
-			// calculate one time series range for all requests from collectors
 			start := time.Now()
-			delta := 10 * time.Minute // get last 10 minutes of samples
-			if inst.lastStart != nil {
-				delta = start.Sub(*inst.lastStart) + interval
-			}
-			tsEnd := start
-			tsStart := tsEnd.Add(-delta)
-			inst.logger.Info().Time("start", tsStart).Time("end", tsEnd).Str("delta", delta.String()).Msg("collection time series range")
-
+			timespan := inst.nextTimespan(start, services.TimeRange{})
+			resume := inst.lastStart == nil
 			inst.lastStart = &start
 			inst.running = true
 			inst.Unlock()
 
-			timespan := collectors.MetricTimespan{
-				Start:  tsStart,
-				End:    tsEnd,
-				Period: inst.period,
-			}
+			go inst.collect(sess, timespan, start, resume)
+		}
+	}
+}
 
-			go func() {
-				for _, c := range inst.collectors {
-					if err := c.Collect(sess, timespan, inst.baseTags); err != nil {
-						inst.check.ReportError(errors.WithMessage(err, fmt.Sprintf("id: %s, collector: %s", inst.cfg.ID, c.ID())))
-						inst.logger.Warn().Err(err).Str("collector", c.ID()).Msg("collecting telemetry")
-						// need to determine which errors from the various
-						// cloud service providers are fatal vs retry vs ???
-					}
-					if inst.done() {
-						break
-					}
-				}
+// CollectOnce runs a single collection for the time range tr, waits for it
+// to complete, and returns the outcome (see services.Service.CollectOnce).
+func (inst *Instance) CollectOnce(tr services.TimeRange) services.CollectionResult {
+	result := services.CollectionResult{
+		ID:         inst.cfg.ID,
+		ConfigFile: inst.cfgFile,
+		Region:     inst.regionCfg.Name,
+	}
 
-				inst.Lock()
-				inst.running = false
-				inst.Unlock()
-				inst.logger.Info().Str("duration", time.Since(start).String()).Msg("collection complete")
-			}()
+	inst.Lock()
+	if inst.running {
+		inst.Unlock()
+		result.Err = errors.New("collection already in progress")
+		return result
+	}
+	sess, err := inst.createSession(inst.regionCfg.Name)
+	if err != nil {
+		inst.Unlock()
+		result.Err = errors.Wrap(err, "creating AWS SDK session")
+		return result
+	}
+	start := time.Now()
+	timespan := inst.nextTimespan(start, tr)
+	resume := inst.lastStart == nil && tr.Start.IsZero()
+	inst.lastStart = &start
+	inst.running = true
+	inst.Unlock()
+
+	result.Collectors = inst.collect(sess, timespan, start, resume)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// nextTimespan calculates one time series range for all requests from
+// collectors, for a collection starting at start. A non-zero tr.Start or
+// tr.End overrides the calculated range. The caller must hold the instance lock.
+func (inst *Instance) nextTimespan(start time.Time, tr services.TimeRange) collectors.MetricTimespan {
+	tsEnd := start
+	if !tr.End.IsZero() {
+		tsEnd = tr.End
+	}
+	interval := time.Duration(inst.interval) * time.Second
+	delta := 10 * time.Minute // get last 10 minutes of samples
+	if inst.lastStart != nil {
+		delta = start.Sub(*inst.lastStart) + interval
+	}
+	if !tr.Start.IsZero() {
+		delta = tsEnd.Sub(tr.Start)
+	}
+	tsStart := tsEnd.Add(-delta)
+	inst.logger.Info().Time("start", tsStart).Time("end", tsEnd).Str("delta", delta.String()).Msg("collection time series range")
+
+	return collectors.MetricTimespan{
+		Start:  tsStart,
+		End:    tsEnd,
+		Period: inst.period,
+	}
+}
+
+// collect runs each collector for timespan (see services.RunCollection), the
+// collection started at start. If resume is true (the first collection since
+// the instance was created), each collector's timespan starts from its
+// persisted watermark, if any. Returns the outcome of each collector.
+func (inst *Instance) collect(sess *session.Session, timespan collectors.MetricTimespan, start time.Time, resume bool) []services.CollectorResult {
+	cs := make([]services.InstanceCollector, len(inst.collectors))
+	for i, c := range inst.collectors {
+		c := c
+		cs[i] = services.InstanceCollector{
+			ID: c.ID(),
+			Collect: func(start, end time.Time) error {
+				return c.Collect(sess, collectors.MetricTimespan{Start: start, End: end, Period: timespan.Period}, inst.baseTags)
+			},
 		}
 	}
+
+	results, duration := services.RunCollection(services.Collection{
+		Start:        start,
+		RangeStart:   timespan.Start,
+		RangeEnd:     timespan.End,
+		Check:        inst.check,
+		Watermarks:   inst.watermarks,
+		Stopped:      inst.done,
+		ID:           inst.cfg.ID,
+		WatermarkKey: []string{"aws", inst.cfg.ID, inst.regionCfg.Name},
+		Logger:       inst.logger,
+		Overlap:      time.Duration(inst.interval) * time.Second,
+		Resume:       resume,
+	}, cs)
+
+	inst.Lock()
+	inst.running = false
+	inst.lastDuration = duration
+	inst.Unlock()
+
+	return results
+}
+
+// Status returns the current state of the instance.
+func (inst *Instance) Status() services.InstanceStatus {
+	inst.Lock()
+	defer inst.Unlock()
+
+	status := services.InstanceStatus{
+		ID:         inst.cfg.ID,
+		ConfigFile: inst.cfgFile,
+		Region:     inst.regionCfg.Name,
+		Running:    inst.running,
+	}
+	if inst.lastStart != nil {
+		ls := *inst.lastStart
+		status.LastStart = &ls
+	}
+	if inst.lastDuration > 0 {
+		status.LastDuration = inst.lastDuration.String()
+	}
+	for _, c := range inst.collectors {
+		status.Collectors = append(status.Collectors, c.Status())
+	}
+
+	return status
+}
+
+// Stop the instance, any collection in progress will be abandoned.
+func (inst *Instance) Stop() {
+	inst.logger.Info().Msg("stopping client")
+	inst.cancel()
+	services.CloseCheck(inst.check, inst.sink, inst.logger)
 }
 
 // done is a utility routine to check the context, returns true if done.
@@ -0,0 +1,68 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package awsservice
+
+import (
+	"fmt"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/awsservice/collectors"
+)
+
+// ValidateConfigFile returns the problems found with an AWS instance
+// configuration file: keys which are not options, values of the wrong type,
+// missing required settings and invalid values. No AWS or Circonus API calls
+// are made.
+func ValidateConfigFile(cfgFile string) []error {
+	var cfg Config
+	if err := config.LoadConfigFileStrict(cfgFile, &cfg); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	if err := services.ValidateID(cfg.ID); err != nil {
+		errs = append(errs, config.FieldErrorf("id", "%s", err))
+	}
+
+	switch cfg.Period {
+	case "", "basic", "detailed":
+	default:
+		errs = append(errs, config.FieldErrorf("period", "invalid period (%s), expected basic or detailed", cfg.Period))
+	}
+
+	switch {
+	case cfg.AWS.Role != "" && cfg.AWS.AccessKeyID != "":
+		errs = append(errs, config.FieldErrorf("aws", "configure only one of role or access_key_id"))
+	case cfg.AWS.AccessKeyID != "" && cfg.AWS.SecretAccessKey == "":
+		errs = append(errs, config.FieldErrorf("aws.secret_access_key", "required with access_key_id"))
+	case cfg.AWS.AccessKeyID == "" && cfg.AWS.SecretAccessKey != "":
+		errs = append(errs, config.FieldErrorf("aws.access_key_id", "required with secret_access_key"))
+	}
+
+	if len(cfg.Regions) == 0 {
+		errs = append(errs, config.FieldErrorf("regions", "required, at least one region"))
+	}
+	for i, region := range cfg.Regions {
+		field := fmt.Sprintf("regions[%d]", i)
+		if region.Name == "" {
+			errs = append(errs, config.FieldErrorf(field+".name", "required"))
+		}
+		if len(region.Services) == 0 {
+			errs = append(errs, config.FieldErrorf(field+".services", "required, at least one service"))
+		}
+		errs = append(errs, config.PrefixFieldErrors(field+".services", collectors.Validate(region.Services))...)
+		errs = append(errs, services.ValidateTags(field+".tags", region.Tags)...)
+	}
+
+	errs = append(errs, config.PrefixFieldErrors("circonus", cfg.Circonus.Validate())...)
+	errs = append(errs, services.ValidateTags("tags", cfg.Tags)...)
+	for i, sc := range cfg.Sinks {
+		errs = append(errs, config.PrefixFieldErrors(fmt.Sprintf("sinks[%d]", i), sc.Validate())...)
+	}
+
+	return errs
+}
@@ -12,11 +12,13 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -33,6 +35,11 @@ const (
 	// KeyConfDir defines the azure configuration directory.
 	KeyConfDir = "azure.conf_dir"
 
+	// KeyWatchConfDir toggles watching the Azure configuration directory for changes.
+	KeyWatchConfDir = "azure.watch_conf_dir"
+	// DefaultWatchConfDir defines the default setting.
+	DefaultWatchConfDir = true
+
 	// KeyConfExample shows an example configuration.
 	KeyConfExample = "azure.config_example"
 )
@@ -46,9 +53,15 @@ var (
 type AzureService struct {
 	groupCtx  context.Context
 	group     *errgroup.Group
+	confDir   string
 	instances []*Instance
+	scanMu    sync.Mutex
 	logger    zerolog.Logger
+	sync.Mutex
 	enabled   bool
+	started   bool
+	watch     bool
+	stateless bool // one-off collections which do not use the state file (see NewFromFile)
 }
 
 // New returns an Azure cloud service metric collection client.
@@ -63,6 +76,7 @@ func New(ctx context.Context) (*AzureService, error) {
 	g, gctx := errgroup.WithContext(ctx)
 	svc := AzureService{
 		enabled:   viper.GetBool(KeyEnabled),
+		watch:     viper.GetBool(KeyWatchConfDir),
 		group:     g,
 		groupCtx:  gctx,
 		instances: make([]*Instance, 0),
@@ -75,12 +89,12 @@ func New(ctx context.Context) (*AzureService, error) {
 
 	svc.logger.Debug().Msg("Azure enabled, initializing client")
 
-	confDir := viper.GetString(KeyConfDir)
-	if confDir == "" {
-		confDir = DefaultConfDir
+	svc.confDir = viper.GetString(KeyConfDir)
+	if svc.confDir == "" {
+		svc.confDir = DefaultConfDir
 	}
 
-	if err := svc.initInstances(confDir); err != nil {
+	if err := svc.initInstances(svc.confDir); err != nil {
 		return nil, errors.Wrap(err, "initializing Azure metric collector instances(s)")
 	}
 
@@ -92,14 +106,168 @@ func New(ctx context.Context) (*AzureService, error) {
 	return &svc, nil
 }
 
+// NewFromFile returns an Azure service with the instance defined by a single
+// configuration file, for one-off collections (see CollectOnce). The service
+// is enabled regardless of the azure.enabled setting, and does not watch for
+// configuration changes. The state file (collection watermarks) is only used
+// if resume is true.
+func NewFromFile(ctx context.Context, cfgFile string, resume bool) (*AzureService, error) {
+	if cfgFile == "" {
+		return nil, errors.New("invalid config file (empty)")
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	svc := AzureService{
+		enabled:   true,
+		stateless: !resume,
+		group:     g,
+		groupCtx:  gctx,
+		confDir:   filepath.Dir(cfgFile),
+		logger:    log.With().Str("pkg", "azure").Logger(),
+	}
+
+	instance, err := svc.instanceFromConfig(cfgFile, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing Azure metric collector instance")
+	}
+	svc.instances = []*Instance{instance}
+
+	return &svc, nil
+}
+
 // Enabled indicates whether the Azure service is enabled.
 func (svc *AzureService) Enabled() bool {
 	return svc.enabled
 }
 
 // Scan checks the service config directory for configurations and loads them.
+// Instances for new configuration files are started, instances whose configuration
+// file was removed are stopped, and instances whose configuration file changed are
+// restarted. Instances with unchanged configurations are not affected.
 func (svc *AzureService) Scan() error {
-	return errors.New("not implemented")
+	if !svc.enabled {
+		svc.logger.Info().Msg("Azure client disabled, not checking for configurations")
+		return nil
+	}
+	svc.logger.Info().Str("conf_dir", svc.confDir).Msg("Azure client checking for configuration(s)")
+
+	// serialize scans (e.g. SIGHUP and config dir watcher), the service lock
+	// is only held while reading/updating the list of instances so that
+	// status requests are not blocked while new instances are initialized
+	svc.scanMu.Lock()
+	defer svc.scanMu.Unlock()
+
+	files, err := services.ConfigFiles(svc.confDir)
+	if err != nil {
+		return errors.Wrap(err, "scanning Azure config dir")
+	}
+
+	svc.Lock()
+	existingInstances := svc.instances
+	svc.Unlock()
+
+	current := make(map[string]*Instance)
+	for _, inst := range existingInstances {
+		current[inst.cfgFile] = inst
+	}
+
+	instances := make([]*Instance, 0, len(existingInstances))
+	created := make([]*Instance, 0)
+	stopped := make([]*Instance, 0)
+	seen := make(map[string]bool)
+
+	for _, cf := range files {
+		seen[cf.Path] = true
+		existing, found := current[cf.Path]
+		if found && existing.cfgHash == cf.Hash {
+			instances = append(instances, existing)
+			continue
+		}
+
+		// create the replacement instance before stopping the existing one, so
+		// that an invalid configuration does not stop a running collection instance
+		instance, err := svc.instanceFromConfig(cf.Path, cf.Hash)
+		if err != nil {
+			svc.logger.Error().Err(err).Str("config_file", cf.Path).Msg("skipping")
+			if found {
+				instances = append(instances, existing)
+			}
+			continue
+		}
+
+		if found {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("config changed, restarting instance")
+			stopped = append(stopped, existing)
+		} else {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("new config, starting instance")
+		}
+
+		instances = append(instances, instance)
+		created = append(created, instance)
+	}
+
+	for cfgFile, existing := range current {
+		if seen[cfgFile] {
+			continue
+		}
+		svc.logger.Info().Str("config_file", cfgFile).Msg("config removed, stopping instance")
+		stopped = append(stopped, existing)
+	}
+
+	for _, inst := range stopped {
+		inst.Stop()
+	}
+
+	svc.Lock()
+	svc.instances = instances
+	if svc.started {
+		for _, inst := range created {
+			svc.group.Go(inst.Start)
+		}
+	}
+	svc.Unlock()
+
+	return nil
+}
+
+// Status returns the current state of each Azure subscription instance.
+func (svc *AzureService) Status() []services.InstanceStatus {
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	status := make([]services.InstanceStatus, 0, len(instances))
+	for _, inst := range instances {
+		status = append(status, inst.Status())
+	}
+
+	return status
+}
+
+// CollectOnce runs a single collection for each Azure subscription instance, concurrently,
+// for the time range tr, waits for them to complete and returns the outcome
+// of each.
+func (svc *AzureService) CollectOnce(tr services.TimeRange) []services.CollectionResult {
+	if !svc.enabled {
+		return nil
+	}
+
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	results := make([]services.CollectionResult, len(instances))
+	var wg sync.WaitGroup
+	for idx, inst := range instances {
+		wg.Add(1)
+		go func(idx int, inst *Instance) {
+			defer wg.Done()
+			results[idx] = inst.CollectOnce(tr)
+		}(idx, inst)
+	}
+	wg.Wait()
+
+	return results
 }
 
 // Start begins collecting metrics from Azure.
@@ -111,16 +279,26 @@ func (svc *AzureService) Start() error {
 
 	svc.logger.Info().Msg("Azure client starting")
 
+	svc.Lock()
 	for _, instance := range svc.instances {
 		inst := instance
 		svc.group.Go(inst.Start)
 	}
+	svc.started = true
+	svc.Unlock()
+
+	if svc.watch {
+		svc.group.Go(func() error {
+			if err := services.WatchConfigDir(svc.groupCtx, svc.confDir, svc.Scan, svc.logger); err != nil {
+				svc.logger.Error().Err(err).Msg("config changes will require a SIGHUP to be applied")
+			}
+			return nil
+		})
+	}
 
-	go func() {
-		if err := svc.group.Wait(); err != nil {
-			svc.logger.Error().Err(err).Msg("waiting for service group")
-		}
-	}()
+	// instances may be added/removed by Scan, wait for the service
+	// to be stopped rather than for the current set of instances
+	<-svc.groupCtx.Done()
 
 	return svc.group.Wait()
 }
@@ -130,23 +308,15 @@ func (svc *AzureService) initInstances(confDir string) error {
 		return errors.New("invalid config dir (empty)")
 	}
 
-	entries, err := os.ReadDir(confDir)
+	files, err := services.ConfigFiles(confDir)
 	if err != nil {
-		return errors.Wrap(err, "reading AWS config dir")
+		return errors.Wrap(err, "reading Azure config dir")
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if !strings.Contains(".json|.toml|.yaml", filepath.Ext(entry.Name())) { //nolint:gocritic
-			continue
-		}
-
-		cfgFile := path.Join(confDir, entry.Name())
-		instance, err := svc.instanceFromConfig(cfgFile)
+	for _, cf := range files {
+		instance, err := svc.instanceFromConfig(cf.Path, cf.Hash)
 		if err != nil {
-			svc.logger.Error().Err(err).Str("config_file", cfgFile).Msg("skipping")
+			svc.logger.Error().Err(err).Str("config_file", cf.Path).Msg("skipping")
 			continue
 		}
 
@@ -160,23 +330,34 @@ func (svc *AzureService) initInstances(confDir string) error {
 	return nil
 }
 
-func (svc *AzureService) instanceFromConfig(cfgFile string) (*Instance, error) {
+// instanceFromConfig creates an Instance from a configuration file. cfgHash
+// identifies the version of the configuration file the instance was created from.
+func (svc *AzureService) instanceFromConfig(cfgFile, cfgHash string) (*Instance, error) {
 	var cfg Config
 	if err := config.LoadConfigFile(cfgFile, &cfg); err != nil {
 		return nil, errors.Wrap(err, "loading config file")
 	}
 
-	if cfg.ID == "" {
-		return nil, errors.New("invalid config ID (empty)")
-	}
-	if strings.Contains(cfg.ID, " ") {
-		return nil, errors.New("invalid config ID (contains spaces)")
+	if err := services.ValidateID(cfg.ID); err != nil {
+		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(svc.groupCtx)
 	instance := &Instance{
-		cfg:    &cfg,
-		ctx:    svc.groupCtx,
-		logger: svc.logger.With().Str("id", cfg.ID).Logger(),
+		cfg:     &cfg,
+		cfgFile: cfgFile,
+		cfgHash: cfgHash,
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  svc.logger.With().Str("id", cfg.ID).Logger(),
+	}
+
+	if !svc.stateless {
+		wm, err := services.ConfiguredWatermarks()
+		if err != nil {
+			instance.logger.Warn().Err(err).Msg("loading collection state, collections will not resume after restart")
+		}
+		instance.watermarks = wm
 	}
 
 	// handle config settings and/or overlay defaults
@@ -192,22 +373,16 @@ func (svc *AzureService) instanceFromConfig(cfgFile string) (*Instance, error) {
 
 	sm, err := instance.getSubscriptionMeta()
 	if err != nil {
+		cancel()
 		return nil, errors.Wrap(err, "init instance, azure subscription info")
 	}
 
 	instance.logger.Info().Str("subscription", sm.Name).Msg("creating instance")
 
-	checkConfig := &circonus.Config{
-		ID:            fmt.Sprintf("azure_%s", cfg.ID),
-		DisplayName:   fmt.Sprintf("azure %s %s /%s", cfg.ID, sm.Name, release.NAME),
-		CheckBundleID: cfg.Circonus.CID,
-		APIKey:        cfg.Circonus.Key,
-		APIApp:        cfg.Circonus.App,
-		APIURL:        cfg.Circonus.URL,
-		Debug:         cfg.Circonus.Debug,
-		Logger:        instance.logger,
-		Tags:          fmt.Sprintf("%s:azure", release.NAME),
-	}
+	checkConfig := services.CheckConfig(cfg.Circonus, instance.logger)
+	checkConfig.ID = fmt.Sprintf("azure_%s", cfg.ID)
+	checkConfig.DisplayName = fmt.Sprintf("azure %s %s /%s", cfg.ID, sm.Name, release.NAME)
+	checkConfig.Tags = fmt.Sprintf("%s:azure", release.NAME)
 	if len(cfg.Tags) > 0 { // if top-level tags are configured, add them to check
 		tags := make([]string, len(cfg.Tags))
 		for idx, tag := range cfg.Tags {
@@ -218,9 +393,18 @@ func (svc *AzureService) instanceFromConfig(cfgFile string) (*Instance, error) {
 
 	chk, err := circonus.NewCheck("azure", checkConfig)
 	if err != nil {
+		cancel()
 		return nil, errors.Wrap(err, "creating/retrieving circonus check")
 	}
 	instance.check = chk
 
+	sink, err := services.NewSink(chk, cfg.Sinks, instance.logger)
+	if err != nil {
+		cancel()
+		chk.Close()
+		return nil, errors.Wrap(err, "creating metric sinks")
+	}
+	instance.sink = sink
+
 	return instance, nil
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/sinks"
 	toml "github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
@@ -23,6 +24,7 @@ type Config struct {
 	Azure    AzureConfig            `json:"azure" toml:"azure" yaml:"azure"`          // REQUIRED, azure configuration
 	Circonus circonus.ServiceConfig `json:"circonus" toml:"circonus" yaml:"circonus"` // REQUIRED, circonus config: api credentials, check, broker, etc.
 	Tags     circonus.Tags          `json:"tags" toml:"tags" yaml:"tags"`             // global tags, added to all metrics
+	Sinks    []sinks.Config         `json:"sinks" toml:"sinks" yaml:"sinks"`          // additional destinations for metric samples (file, stdout, http)
 }
 
 // AzureConfig defines the Azure sdk credentials.
@@ -6,13 +6,14 @@
 package azureservice
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -21,12 +22,18 @@ import (
 // Note: a Instance has a 1:1 relation with azure:circ - each Instance has (or, may have)
 // a different set of azure and/or circonus credentials.
 type Instance struct {
-	ctx       context.Context
-	cfg       *Config
-	check     *circonus.Check
-	lastStart *time.Time
-	baseTags  circonus.Tags
-	logger    zerolog.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+	cfg          *Config
+	cfgFile      string
+	cfgHash      string
+	check        *circonus.Check
+	sink         circonus.Sink
+	lastStart    *time.Time
+	watermarks   *services.Watermarks
+	lastDuration time.Duration
+	baseTags     circonus.Tags
+	logger       zerolog.Logger
 	sync.Mutex
 	running bool
 }
@@ -62,31 +69,88 @@ func (inst *Instance) Start() error {
 				continue
 			}
 
-			// calculate one time series range for all requests from collectors
 			start := time.Now()
-
+			resume := inst.lastStart == nil
 			inst.lastStart = &start
 			inst.running = true
 			inst.Unlock()
 
-			err := inst.collect(start.UTC())
-			if err != nil {
-				inst.check.ReportError(errors.WithMessage(err, fmt.Sprintf("id: %s", inst.cfg.ID)))
-				inst.logger.Warn().Err(err).Msg("collecting metrics")
-				// if fatal return the error
-				// need to determine which errors from the various cloud service providers are fatal vs retry vs wait for next iteration
+			inst.run(start, services.TimeRange{}, resume)
+			if inst.done() {
+				return nil
 			}
-
-			inst.Lock()
-			inst.running = false
-			inst.Unlock()
-			inst.logger.Info().Str("duration", time.Since(start).String()).Msg("collection complete")
 		}
 	}
 }
 
-// collect metrics from Azure and forward to Circonus using buffer.
-func (inst *Instance) collect(endTime time.Time) error {
+// CollectOnce runs a single collection for the time range tr, waits for it
+// to complete, and returns the outcome (see services.Service.CollectOnce).
+// Azure does not use collectors, the result has a single collector with an
+// empty id.
+func (inst *Instance) CollectOnce(tr services.TimeRange) services.CollectionResult {
+	result := services.CollectionResult{
+		ID:           inst.cfg.ID,
+		ConfigFile:   inst.cfgFile,
+		Subscription: inst.cfg.Azure.SubscriptionID,
+	}
+
+	inst.Lock()
+	if inst.running {
+		inst.Unlock()
+		result.Err = errors.New("collection already in progress")
+		return result
+	}
+	start := time.Now()
+	resume := inst.lastStart == nil && tr.Start.IsZero()
+	inst.lastStart = &start
+	inst.running = true
+	inst.Unlock()
+
+	result.Collectors = []services.CollectorResult{inst.run(start, tr, resume)}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// run collects metrics (see services.RunCollection), the collection started
+// at start. A non-zero tr.Start or tr.End overrides the default range (see
+// getResourceMetrics) ending at start. If resume is true (the first
+// collection since the instance was created), the range starts from the
+// persisted watermark, if any. Returns the outcome of the collection.
+func (inst *Instance) run(start time.Time, tr services.TimeRange, resume bool) services.CollectorResult {
+	endTime := start
+	if !tr.End.IsZero() {
+		endTime = tr.End
+	}
+
+	results, duration := services.RunCollection(services.Collection{
+		Start:        start,
+		RangeStart:   tr.Start,
+		RangeEnd:     endTime,
+		Check:        inst.check,
+		Watermarks:   inst.watermarks,
+		Stopped:      inst.done,
+		ID:           inst.cfg.ID,
+		WatermarkKey: []string{"azure", inst.cfg.ID},
+		Logger:       inst.logger,
+		Overlap:      time.Duration(inst.cfg.Azure.Interval) * time.Minute,
+		Resume:       resume,
+	}, []services.InstanceCollector{{
+		Collect: func(startTime, endTime time.Time) error {
+			return inst.collect(startTime.UTC(), endTime.UTC())
+		},
+	}})
+
+	inst.Lock()
+	inst.running = false
+	inst.lastDuration = duration
+	inst.Unlock()
+
+	return results[0]
+}
+
+// collect metrics from Azure, for startTime through endTime (see
+// getResourceMetrics), and forward to Circonus using buffer.
+func (inst *Instance) collect(startTime, endTime time.Time) error {
 	// NOTE: this model needs to be used, so submission requests will have
 	// a Content-Length while streaming JSON data:
 	//
@@ -116,15 +180,15 @@ func (inst *Instance) collect(endTime time.Time) error {
 		return nil
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(32678)
+	buf := inst.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 
 	for _, resource := range resources {
 		if inst.done() {
 			break
 		}
 
-		err := inst.getResourceMetrics(&buf, auth, resource.ID, endTime, resource.Tags)
+		err := inst.getResourceMetrics(buf, auth, resource.ID, startTime, endTime, resource.Tags)
 		if err != nil {
 			inst.check.ReportError(errors.WithMessage(err, fmt.Sprintf("id: %s, resource_id: %s", inst.cfg.ID, resource.ID)))
 			inst.logger.Warn().Err(err).Str("resource_id", resource.ID).Msg("collecting metrics")
@@ -140,17 +204,68 @@ func (inst *Instance) collect(endTime time.Time) error {
 		}
 
 		inst.logger.Debug().Str("resource_id", resource.ID).Msg("submitting telemetry")
-		if err := inst.check.SubmitMetrics(&buf); err != nil {
+		if err := buf.Submit(); err != nil {
 			inst.check.ReportError(errors.WithMessage(err, fmt.Sprintf("id: %s, resource_id: %s", inst.cfg.ID, resource.ID)))
 			inst.logger.Error().Err(err).Str("resource_id", resource.ID).Msg("submitting telemetry")
 		}
-
-		buf.Reset()
 	}
 
 	return nil
 }
 
+// Status returns the current state of the instance.
+func (inst *Instance) Status() services.InstanceStatus {
+	inst.Lock()
+	defer inst.Unlock()
+
+	status := services.InstanceStatus{
+		ID:           inst.cfg.ID,
+		ConfigFile:   inst.cfgFile,
+		Subscription: inst.cfg.Azure.SubscriptionID,
+		Running:      inst.running,
+	}
+	if inst.lastStart != nil {
+		ls := *inst.lastStart
+		status.LastStart = &ls
+	}
+	if inst.lastDuration > 0 {
+		status.LastDuration = inst.lastDuration.String()
+	}
+
+	return status
+}
+
+// Stop the instance, any collection in progress will be abandoned.
+func (inst *Instance) Stop() {
+	inst.logger.Info().Msg("stopping client")
+	inst.cancel()
+	services.CloseCheck(inst.check, inst.sink, inst.logger)
+}
+
+// recordAPICall tracks a call to an azure api, and the http status code if
+// the call failed, in the check's self-telemetry.
+func (inst *Instance) recordAPICall(err error) {
+	if inst.check == nil {
+		return
+	}
+	inst.check.RecordAPICall("", azureErrorCode(err))
+}
+
+// azureErrorCode returns the http status code for err, "unknown" if there is
+// no status code, or an empty string if there was no error.
+func azureErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var detailedErr autorest.DetailedError
+	if errors.As(err, &detailedErr) && detailedErr.StatusCode != nil {
+		if code := fmt.Sprintf("%v", detailedErr.StatusCode); code != "0" {
+			return code
+		}
+	}
+	return "unknown"
+}
+
 // done is a utility routine to check the context, returns true if done.
 func (inst *Instance) done() bool {
 	select {
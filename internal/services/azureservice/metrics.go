@@ -7,7 +7,6 @@ package azureservice
 
 import (
 	"fmt"
-	"io"
 	"strings"
 	"time"
 
@@ -18,11 +17,13 @@ import (
 )
 
 // getResourceMetrics collects metrics using azure api for a given resource id
-// and writes them to the metric destination.
+// and writes them to the metric destination. If startTime is zero, the last
+// ten samples, at each metric's granularity, before endTime are collected.
 func (inst *Instance) getResourceMetrics(
-	metricDest io.Writer,
+	metricDest circonus.Batch,
 	auth autorest.Authorizer,
 	resourceID string,
+	startTime time.Time,
 	endTime time.Time,
 	resourceTags circonus.Tags) error {
 
@@ -56,7 +57,7 @@ func (inst *Instance) getResourceMetrics(
 			}
 
 			for _, metricGroup := range metricGroups {
-				err := inst.handleMetricGroup(metricDest, auth, resourceID, resourceTags, endTime, granularity, aggregation, metricGroup)
+				err := inst.handleMetricGroup(metricDest, auth, resourceID, resourceTags, startTime, endTime, granularity, aggregation, metricGroup)
 				if err != nil {
 					inst.logger.Error().
 						Err(err).
@@ -77,16 +78,17 @@ func (inst *Instance) getResourceMetrics(
 // azure api limit) and handles processing and writing each metric sample to the
 // metric destination.
 func (inst *Instance) handleMetricGroup(
-	metricDest io.Writer,
+	metricDest circonus.Batch,
 	auth autorest.Authorizer,
 	resourceID string,
 	resourceTags circonus.Tags,
+	startTime time.Time,
 	endTime time.Time,
 	granularity string,
 	aggregation string,
 	metricGroup []string) error {
 
-	metricData, err := inst.getMetricData(auth, resourceID, endTime, granularity, aggregation, metricGroup)
+	metricData, err := inst.getMetricData(auth, resourceID, startTime, endTime, granularity, aggregation, metricGroup)
 	if err != nil {
 		return errors.Wrap(err, "fetching metric samples")
 	}
@@ -113,7 +115,7 @@ func (inst *Instance) handleMetricGroup(
 
 			sample := sample
 
-			err := inst.check.WriteMetricSample(metricDest, encodedMetricName, sample.Type, sample.Value, &sample.Timestamp)
+			err := metricDest.WriteMetricSample(encodedMetricName, sample.Type, sample.Value, &sample.Timestamp)
 			if err != nil {
 				inst.logger.Warn().
 					Err(err).
@@ -146,6 +148,7 @@ type metricData map[string]metricSamples
 func (inst *Instance) getMetricData(
 	auth autorest.Authorizer,
 	resourceID string,
+	startTime time.Time,
 	endTime time.Time,
 	granularity string,
 	aggregation string,
@@ -173,12 +176,14 @@ func (inst *Instance) getMetricData(
 		timeUnit = time.Hour
 	}
 
-	timeDelta := timeUnit * 10 // get last 10 samples
-
-	startTime := endTime.Add(-timeDelta)
+	if startTime.IsZero() {
+		timeDelta := timeUnit * 10 // get last 10 samples
+		startTime = endTime.Add(-timeDelta)
+	}
 	timespan := fmt.Sprintf("%s/%s", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
 
 	resp, err := metricsClient.List(inst.ctx, resourceID, timespan, &granularity, strings.Join(metricList, ","), aggregation, nil, "", "", insights.Data, "")
+	inst.recordAPICall(err)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +256,7 @@ func (inst *Instance) getMetricList(auth autorest.Authorizer, resourceID string)
 	}
 	// ref: https://godoc.org/github.com/Azure/azure-sdk-for-go/services/preview/monitor/mgmt/2018-09-01/insights#MetricDefinitionsClient.List
 	result, err := metricsDefClient.List(inst.ctx, resourceID, "")
+	inst.recordAPICall(err)
 	if err != nil {
 		return nil, err
 	}
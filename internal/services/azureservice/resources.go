@@ -33,6 +33,7 @@ func (inst *Instance) getResources(auth autorest.Authorizer) ([]resourceMeta, er
 	}
 	// ref: https://godoc.org/github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-05-01/resources#Client.ListComplete
 	result, err := resourceClient.ListComplete(inst.ctx, inst.cfg.Azure.ResourceFilter, "", nil)
+	inst.recordAPICall(err)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +42,7 @@ func (inst *Instance) getResources(auth autorest.Authorizer) ([]resourceMeta, er
 	for result.NotDone() {
 
 		if err := result.NextWithContext(inst.ctx); err != nil {
+			inst.recordAPICall(err)
 			return nil, err
 		}
 
@@ -108,6 +110,7 @@ func (inst *Instance) resourceHasMetrics(auth autorest.Authorizer, resourceID st
 	ts := time.Now().Add(-5 * time.Hour)
 	// ref: https://godoc.org/github.com/Azure/azure-sdk-for-go/services/preview/monitor/mgmt/2018-09-01/insights#MetricNamespacesClient.List
 	result, err := metricsNamespaceClient.List(inst.ctx, resourceID, ts.Format(time.RFC3339))
+	inst.recordAPICall(err)
 	if err != nil {
 		return false, err
 	}
@@ -29,6 +29,7 @@ func (inst *Instance) getSubscriptionMeta() (*subscriptionMeta, error) {
 	}
 	// ref: https://godoc.org/github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2016-06-01/subscriptions#Client.Get
 	result, err := subscriptionClient.Get(inst.ctx, inst.cfg.Azure.SubscriptionID)
+	inst.recordAPICall(err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package azureservice
+
+import (
+	"fmt"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+)
+
+// ValidateConfigFile returns the problems found with an Azure instance
+// configuration file: keys which are not options, values of the wrong type,
+// missing required settings and invalid values. No Azure or Circonus API
+// calls are made.
+func ValidateConfigFile(cfgFile string) []error {
+	var cfg Config
+	if err := config.LoadConfigFileStrict(cfgFile, &cfg); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	if err := services.ValidateID(cfg.ID); err != nil {
+		errs = append(errs, config.FieldErrorf("id", "%s", err))
+	}
+
+	for _, r := range []struct{ field, value string }{
+		{"azure.directory_id", cfg.Azure.DirectoryID},
+		{"azure.application_id", cfg.Azure.ApplicationID},
+		{"azure.application_secret", cfg.Azure.ApplicationSecret},
+		{"azure.subscription_id", cfg.Azure.SubscriptionID},
+	} {
+		if r.value == "" {
+			errs = append(errs, config.FieldErrorf(r.field, "required"))
+		}
+	}
+	if cfg.Azure.CloudName != "" {
+		if _, err := azure.EnvironmentFromName(cfg.Azure.CloudName); err != nil {
+			errs = append(errs, config.FieldErrorf("azure.cloud_name", "unknown azure cloud (%s)", cfg.Azure.CloudName))
+		}
+	}
+	if cfg.Azure.Interval != 0 && cfg.Azure.Interval < defaultInterval {
+		errs = append(errs, config.FieldErrorf("azure.collect_interval", "invalid interval (%d), minimum is %d minutes", cfg.Azure.Interval, defaultInterval))
+	}
+
+	errs = append(errs, config.PrefixFieldErrors("circonus", cfg.Circonus.Validate())...)
+	errs = append(errs, services.ValidateTags("tags", cfg.Tags)...)
+	for i, sc := range cfg.Sinks {
+		errs = append(errs, config.PrefixFieldErrors(fmt.Sprintf("sinks[%d]", i), sc.Validate())...)
+	}
+
+	return errs
+}
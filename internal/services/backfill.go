@@ -0,0 +1,182 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// BackfillOptions defines how a backfill (see Backfill) walks through its time range.
+type BackfillOptions struct {
+	Chunk      time.Duration // size of the time range collected at once, a multiple of a minute (the finest collection period)
+	MaxAPIRate float64       // average cloud api calls per second not to exceed, zero for no limit
+}
+
+// Backfill collects the time range tr from svc (see Service.CollectOnce) in
+// consecutive chunks, oldest first, so that gaps (e.g. while the agent was
+// down) can be filled from the data the cloud service retains. After each
+// chunk it waits, if necessary, so the api calls made average no more than
+// opts.MaxAPIRate per second. Stops early if ctx is done. Returns the
+// combined results of the chunks collected.
+func Backfill(ctx context.Context, svc Service, tr TimeRange, opts BackfillOptions, logger zerolog.Logger) ([]CollectionResult, error) {
+	if svc == nil {
+		return nil, errors.New("invalid service (nil)")
+	}
+	if tr.Start.IsZero() || tr.End.IsZero() {
+		return nil, errors.New("invalid time range, start and end are required")
+	}
+	if !tr.Start.Before(tr.End) {
+		return nil, errors.New("invalid time range, start must be before end")
+	}
+	if opts.Chunk < time.Minute || opts.Chunk%time.Minute != 0 {
+		return nil, errors.Errorf("invalid chunk (%s), must be a multiple of 1m", opts.Chunk)
+	}
+	if opts.MaxAPIRate < 0 {
+		return nil, errors.Errorf("invalid max api rate (%f)", opts.MaxAPIRate)
+	}
+
+	var results []CollectionResult
+	chunks := BackfillChunks(tr, opts.Chunk)
+	for idx, chunk := range chunks {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		start := time.Now()
+		chunkResults := svc.CollectOnce(chunk)
+		elapsed := time.Since(start)
+
+		var samples, apiCalls uint64
+		failed := false
+		for _, r := range chunkResults {
+			failed = failed || r.Failed()
+			for _, c := range r.Collectors {
+				samples += c.Samples
+				apiCalls += c.APICalls
+			}
+		}
+		results = mergeResults(results, chunkResults)
+
+		logger.Info().
+			Int("chunk", idx+1).
+			Int("chunks", len(chunks)).
+			Time("start", chunk.Start).
+			Time("end", chunk.End).
+			Uint64("samples", samples).
+			Uint64("api_calls", apiCalls).
+			Bool("failed", failed).
+			Str("duration", elapsed.Round(time.Millisecond).String()).Msg("backfill chunk collected")
+
+		if idx == len(chunks)-1 || opts.MaxAPIRate == 0 {
+			continue
+		}
+		pause := time.Duration(float64(apiCalls)/opts.MaxAPIRate*float64(time.Second)) - elapsed
+		if pause <= 0 {
+			continue
+		}
+		logger.Debug().Str("pause", pause.Round(time.Millisecond).String()).Msg("limiting api rate")
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+
+	return results, nil
+}
+
+// BackfillChunks splits tr into consecutive time ranges of size chunk, the
+// start is truncated to chunk (e.g. hour boundaries for 1h) so that chunks
+// align with collection periods. The last chunk ends at tr.End.
+func BackfillChunks(tr TimeRange, chunk time.Duration) []TimeRange {
+	if chunk <= 0 || !tr.Start.Before(tr.End) {
+		return nil
+	}
+	var chunks []TimeRange
+	for start := tr.Start.Truncate(chunk); start.Before(tr.End); start = start.Add(chunk) {
+		end := start.Add(chunk)
+		if end.After(tr.End) {
+			end = tr.End
+		}
+		chunks = append(chunks, TimeRange{Start: start, End: end})
+	}
+	return chunks
+}
+
+// FindConfigFile returns the configuration file in confDir for the instance with id.
+func FindConfigFile(confDir, id string) (string, error) {
+	if id == "" {
+		return "", errors.New("invalid id (empty)")
+	}
+	files, err := ConfigFiles(confDir)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		var cfg struct {
+			ID string `json:"id" toml:"id" yaml:"id"`
+		}
+		if err := config.LoadConfigFile(f.Path, &cfg); err != nil {
+			continue
+		}
+		if cfg.ID == id {
+			return f.Path, nil
+		}
+	}
+	return "", errors.Errorf("no configuration with id (%s) found in %s", id, confDir)
+}
+
+// mergeResults adds the results of a chunk to results, combining results for
+// the same instance (id and scope) and collector. Samples, api calls and
+// durations are summed, the most recent error is kept.
+func mergeResults(results, chunk []CollectionResult) []CollectionResult {
+	for _, cr := range chunk {
+		idx := -1
+		for i, r := range results {
+			if r.ID == cr.ID && r.Region == cr.Region && r.Subscription == cr.Subscription && r.Project == cr.Project {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			cr.Collectors = append([]CollectorResult(nil), cr.Collectors...)
+			results = append(results, cr)
+			continue
+		}
+
+		r := &results[idx]
+		r.Duration += cr.Duration
+		if cr.Err != nil {
+			r.Err = cr.Err
+		}
+		for _, cc := range cr.Collectors {
+			found := false
+			for i := range r.Collectors {
+				c := &r.Collectors[i]
+				if c.ID != cc.ID {
+					continue
+				}
+				c.Samples += cc.Samples
+				c.APICalls += cc.APICalls
+				c.Duration += cc.Duration
+				if cc.Err != nil {
+					c.Err = cc.Err
+				}
+				found = true
+				break
+			}
+			if !found {
+				r.Collectors = append(r.Collectors, cc)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,148 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type backfillService struct {
+	ranges []TimeRange
+}
+
+func (s *backfillService) Enabled() bool            { return true }
+func (s *backfillService) Scan() error              { return nil }
+func (s *backfillService) Start() error             { return nil }
+func (s *backfillService) Status() []InstanceStatus { return nil }
+func (s *backfillService) CollectOnce(tr TimeRange) []CollectionResult {
+	s.ranges = append(s.ranges, tr)
+	r := CollectionResult{ID: "prod", Region: "us-east-1", Collectors: []CollectorResult{{ID: "AWS/EC2", Samples: 10, APICalls: 2}}}
+	if len(s.ranges) == 2 {
+		r.Collectors[0].Err = errors.New("throttled")
+	}
+	return []CollectionResult{r}
+}
+
+func TestBackfillChunks(t *testing.T) {
+	t.Log("Testing BackfillChunks")
+
+	base := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Log("\taligned")
+	{
+		chunks := BackfillChunks(TimeRange{Start: base, End: base.Add(3 * time.Hour)}, time.Hour)
+		if len(chunks) != 3 {
+			t.Fatalf("expected 3 chunks, got %d", len(chunks))
+		}
+		if !chunks[2].Start.Equal(base.Add(2*time.Hour)) || !chunks[2].End.Equal(base.Add(3*time.Hour)) {
+			t.Fatalf("unexpected last chunk %v", chunks[2])
+		}
+	}
+
+	t.Log("\tunaligned")
+	{
+		chunks := BackfillChunks(TimeRange{Start: base.Add(20 * time.Minute), End: base.Add(90 * time.Minute)}, time.Hour)
+		if len(chunks) != 2 {
+			t.Fatalf("expected 2 chunks, got %d", len(chunks))
+		}
+		if !chunks[0].Start.Equal(base) {
+			t.Fatalf("expected start truncated to %s, got %s", base, chunks[0].Start)
+		}
+		if !chunks[1].End.Equal(base.Add(90 * time.Minute)) {
+			t.Fatalf("expected last chunk to end at range end, got %s", chunks[1].End)
+		}
+	}
+
+	t.Log("\tinvalid range")
+	{
+		if chunks := BackfillChunks(TimeRange{Start: base, End: base}, time.Hour); len(chunks) != 0 {
+			t.Fatalf("expected no chunks, got %d", len(chunks))
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	t.Log("Testing Backfill")
+
+	base := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	tr := TimeRange{Start: base, End: base.Add(3 * time.Hour)}
+
+	t.Log("\tinvalid options")
+	{
+		if _, err := Backfill(context.Background(), &backfillService{}, TimeRange{Start: base}, BackfillOptions{Chunk: time.Hour}, zerolog.Nop()); err == nil {
+			t.Fatal("expected error for missing end")
+		}
+		if _, err := Backfill(context.Background(), &backfillService{}, tr, BackfillOptions{Chunk: 90 * time.Second}, zerolog.Nop()); err == nil {
+			t.Fatal("expected error for chunk not a multiple of 1m")
+		}
+	}
+
+	t.Log("\tchunks merged")
+	{
+		svc := &backfillService{}
+		results, err := Backfill(context.Background(), svc, tr, BackfillOptions{Chunk: time.Hour, MaxAPIRate: 1000}, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(svc.ranges) != 3 {
+			t.Fatalf("expected 3 collections, got %d", len(svc.ranges))
+		}
+		if len(results) != 1 || len(results[0].Collectors) != 1 {
+			t.Fatalf("expected 1 merged result, got %v", results)
+		}
+		c := results[0].Collectors[0]
+		if c.Samples != 30 || c.APICalls != 6 {
+			t.Fatalf("expected 30 samples and 6 api calls, got %d %d", c.Samples, c.APICalls)
+		}
+		if !results[0].Failed() {
+			t.Fatal("expected failed chunk to fail the result")
+		}
+	}
+
+	t.Log("\tcanceled")
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		svc := &backfillService{}
+		if _, err := Backfill(ctx, svc, tr, BackfillOptions{Chunk: time.Hour}, zerolog.Nop()); err == nil {
+			t.Fatal("expected error")
+		}
+		if len(svc.ranges) != 0 {
+			t.Fatalf("expected no collections, got %d", len(svc.ranges))
+		}
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	t.Log("Testing FindConfigFile")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":"dev"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("id: prod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := FindConfigFile(dir, "prod")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if file != filepath.Join(dir, "b.yaml") {
+		t.Fatalf("unexpected file (%s)", file)
+	}
+
+	if _, err := FindConfigFile(dir, "missing"); err == nil {
+		t.Fatal("expected error")
+	}
+}
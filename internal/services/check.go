@@ -0,0 +1,67 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/sinks"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// CheckConfig returns the check configuration for a service instance, the
+// instance's Circonus configuration (see circonus.NewConfig) combined with
+// the running agent options (submission, spool, dry run). The caller sets
+// the check identity (ID, DisplayName, Tags).
+func CheckConfig(sc circonus.ServiceConfig, logger zerolog.Logger) *circonus.Config {
+	cfg := circonus.NewConfig(sc)
+	cfg.Logger = logger
+	cfg.SubmitRetries = viper.GetInt(config.KeySubmitRetries)
+	cfg.SpoolDir = viper.GetString(config.KeySubmitSpoolDir)
+	cfg.SpoolMaxSize = int64(viper.GetInt(config.KeySubmitSpoolMaxMB)) * 1024 * 1024
+	cfg.SubmitTimeout = viper.GetDuration(config.KeySubmitTimeout)
+	cfg.SubmitIdleTimeout = viper.GetDuration(config.KeySubmitIdleTimeout)
+	cfg.SubmitMaxConns = viper.GetInt(config.KeySubmitMaxConns)
+	cfg.SubmitMaxIdleConns = viper.GetInt(config.KeySubmitMaxIdleConns)
+	cfg.PipeSubmits = viper.GetBool(config.KeyPipeSubmits)
+	cfg.DryRun = viper.GetBool(config.KeyDryRun)
+	cfg.DryRunOutput = viper.GetString(config.KeyDryRunOutput)
+	cfg.Reconcile = viper.GetString(config.KeyCheckReconcile)
+	cfg.MultipleActive = viper.GetString(config.KeyCheckMultipleActive)
+	return cfg
+}
+
+// NewSink returns the sink for a service instance's metric samples, primary
+// (the check) plus any additional sinks configured (not used in dry run mode, to
+// avoid side effects), skipping duplicate samples if enabled.
+func NewSink(primary circonus.Sink, sinkCfgs []sinks.Config, logger zerolog.Logger) (circonus.Sink, error) {
+	if viper.GetBool(config.KeyDryRun) {
+		sinkCfgs = nil // no side effects, samples only go to the dry run output
+	}
+	sink, err := sinks.New(primary, sinkCfgs, logger)
+	if err != nil {
+		return nil, err
+	}
+	if viper.GetBool(config.KeyDedup) {
+		sink = sinks.Dedup(sink, viper.GetDuration(config.KeyDedupRevisionWindow), logger)
+	}
+	return sink, nil
+}
+
+// CloseCheck releases the resources (e.g. connections) held by a service
+// instance's check and sink once the instance is stopped. Either may be nil
+// (e.g. an instance which failed to initialize).
+func CloseCheck(check *circonus.Check, sink circonus.Sink, logger zerolog.Logger) {
+	if sink != nil {
+		if err := sinks.Close(sink); err != nil {
+			logger.Warn().Err(err).Msg("closing metric sinks")
+		}
+	}
+	if check != nil {
+		check.Close()
+	}
+}
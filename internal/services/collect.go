@@ -0,0 +1,98 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TimeRange defines the period a single collection requests metrics for
+// (see Service.CollectOnce). A zero Start or End uses the service default,
+// End is the time the collection starts and Start is based on the service's
+// default lookback (e.g. aws 10 minutes, gcp twice the interval).
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CollectionResult defines the outcome of a single collection by a service
+// instance (see Service.CollectOnce).
+type CollectionResult struct {
+	Err          error             // error preventing the collection (e.g. creating a session), collector errors are in Collectors
+	ID           string            // instance configuration id
+	ConfigFile   string            // configuration file the instance was created from
+	Region       string            // aws only
+	Subscription string            // azure only
+	Project      string            // gcp only
+	Collectors   []CollectorResult // per collector outcome (azure, which does not use collectors, has one with an empty id)
+	Duration     time.Duration     // duration of the collection
+}
+
+// CollectorResult defines the outcome of an individual collector within a collection.
+type CollectorResult struct {
+	Err      error         // collector error, nil if successful
+	ID       string        // collector id (e.g. AWS/EC2 or compute)
+	Samples  uint64        // metric samples written
+	APICalls uint64        // cloud api calls made
+	Duration time.Duration // duration of the collector
+}
+
+// Failed returns true if the collection, or any of its collectors, failed.
+func (r CollectionResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, c := range r.Collectors {
+		if c.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteResults writes a summary, one line per collector, of the collection
+// results for each service (e.g. aws, azure, gcp).
+func WriteResults(w io.Writer, results map[string][]CollectionResult) error {
+	svcIDs := make([]string, 0, len(results))
+	for svcID := range results {
+		svcIDs = append(svcIDs, svcID)
+	}
+	sort.Strings(svcIDs)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tINSTANCE\tSCOPE\tCOLLECTOR\tSAMPLES\tDURATION\tERROR")
+	for _, svcID := range svcIDs {
+		for _, r := range results[svcID] {
+			scope := r.Region + r.Subscription + r.Project
+			if r.Err != nil {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t-\t0\t%s\t%s\n", svcID, r.ID, scope, r.Duration.Round(time.Millisecond), oneLine(r.Err))
+				continue
+			}
+			for _, c := range r.Collectors {
+				id := c.ID
+				if id == "" {
+					id = "-"
+				}
+				errMsg := "-"
+				if c.Err != nil {
+					errMsg = oneLine(c.Err)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", svcID, r.ID, scope, id, c.Samples, c.Duration.Round(time.Millisecond), errMsg)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// oneLine returns the error message with any newlines (e.g. aws sdk errors) replaced.
+func oneLine(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", " ")
+}
@@ -0,0 +1,48 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteResults(t *testing.T) {
+	t.Log("Testing WriteResults")
+
+	results := map[string][]CollectionResult{
+		"gcp": {{ID: "proj", Project: "my-project", Collectors: []CollectorResult{{ID: "compute", Samples: 12}}}},
+		"aws": {
+			{ID: "prod", Region: "us-east-1", Collectors: []CollectorResult{{ID: "AWS/EC2", Samples: 40}, {ID: "AWS/EBS", Err: errors.New("access denied")}}},
+			{ID: "prod", Region: "us-west-2", Err: errors.New("creating session")},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, results); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header and 4 lines, got %d (%s)", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "aws") || !strings.HasPrefix(lines[4], "gcp") {
+		t.Fatalf("expected results ordered by service, got (%s)", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[3] != "AWS/EC2" || fields[4] != "40" {
+		t.Fatalf("unexpected line (%s)", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "access denied") || !strings.HasSuffix(lines[3], "creating session") {
+		t.Fatalf("expected errors, got (%s)", buf.String())
+	}
+
+	if !results["aws"][0].Failed() || results["gcp"][0].Failed() {
+		t.Fatal("expected only the aws result to have failed")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigFile identifies a service instance configuration file and a hash
+// of its contents, used to detect changes when a directory is rescanned.
+type ConfigFile struct {
+	Path string
+	Hash string
+}
+
+// IsConfigFile returns true if the file name has a recognized configuration
+// file extension (.json, .toml, .yaml). Hidden files (e.g. editor lock files
+// such as '.#aws.yaml') are ignored.
+func IsConfigFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	switch filepath.Ext(name) {
+	case ".json", ".toml", ".yaml":
+		return true
+	default:
+		return false
+	}
+}
+
+// ConfigFiles returns the list of configuration files found in confDir,
+// sorted by name.
+func ConfigFiles(confDir string) ([]ConfigFile, error) {
+	if confDir == "" {
+		return nil, errors.New("invalid config dir (empty)")
+	}
+
+	entries, err := os.ReadDir(confDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config dir")
+	}
+
+	files := make([]ConfigFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if !IsConfigFile(entry.Name()) {
+			continue
+		}
+
+		cfgFile := filepath.Join(confDir, entry.Name())
+		hash, err := HashFile(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ConfigFile{Path: cfgFile, Hash: hash})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	return files, nil
+}
+
+// HashFile returns a hex encoded sha256 hash of the contents of a file.
+func HashFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading config file (%s)", file)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
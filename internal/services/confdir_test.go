@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFiles(t *testing.T) {
+	t.Log("Testing ConfigFiles")
+
+	t.Log("invalid (empty)")
+	{
+		_, err := ConfigFiles("")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b.yaml":    "id: b",
+		"a.json":    `{"id":"a"}`,
+		"c.toml":    `id = "c"`,
+		"README.md": "ignored",
+		".#b.yaml":  "ignored",
+		"noext":     "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("writing test file (%s)", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0o700); err != nil {
+		t.Fatalf("creating test dir (%s)", err)
+	}
+
+	t.Log("valid")
+	{
+		files, err := ConfigFiles(dir)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(files) != 3 {
+			t.Fatalf("expected 3 files, got %d (%v)", len(files), files)
+		}
+		for idx, name := range []string{"a.json", "b.yaml", "c.toml"} {
+			if filepath.Base(files[idx].Path) != name {
+				t.Fatalf("expected %s, got %s", name, files[idx].Path)
+			}
+			if files[idx].Hash == "" {
+				t.Fatalf("expected hash for %s", name)
+			}
+		}
+	}
+
+	t.Log("changed content")
+	{
+		before, err := HashFile(filepath.Join(dir, "a.json"))
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":"aa"}`), 0o600); err != nil {
+			t.Fatalf("writing test file (%s)", err)
+		}
+		after, err := HashFile(filepath.Join(dir, "a.json"))
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if before == after {
+			t.Fatal("expected hash to change")
+		}
+	}
+}
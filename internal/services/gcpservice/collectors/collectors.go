@@ -7,18 +7,24 @@ package collectors
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/status"
 )
 
 // Collector interface for gcp metric services.
 type Collector interface {
 	Collect(timeseriesStart, timeseriesEnd time.Time, projectID string, creds []byte, baseTags circonus.Tags) error
 	ID() string
+	Status() services.CollectorStatus
 }
 
 // GCPCollector defines a generic gcp service metric collector.
@@ -36,7 +42,7 @@ type Filter struct {
 }
 
 // New creates a new collector instance.
-func New(ctx context.Context, check *circonus.Check, cfgs []GCPCollector, interval time.Duration, logger zerolog.Logger) ([]Collector, error) {
+func New(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfgs []GCPCollector, interval time.Duration, logger zerolog.Logger) ([]Collector, error) {
 	cl := collectorList()
 	cc := []Collector{}
 
@@ -58,7 +64,7 @@ func New(ctx context.Context, check *circonus.Check, cfgs []GCPCollector, interv
 		var err error
 
 		if initfn, known := cl[strings.ToLower(cfg.Name)]; known {
-			c, err = initfn(ctx, check, &cfg, interval, logger)
+			c, err = initfn(ctx, check, sink, &cfg, interval, logger)
 		} else {
 			err = errors.New("unrecognized aws service namespace")
 		}
@@ -83,7 +89,7 @@ func New(ctx context.Context, check *circonus.Check, cfgs []GCPCollector, interv
 	return cc, nil
 }
 
-type collectorInitFn func(context.Context, *circonus.Check, *GCPCollector, time.Duration, zerolog.Logger) (Collector, error)
+type collectorInitFn func(context.Context, *circonus.Check, circonus.Sink, *GCPCollector, time.Duration, zerolog.Logger) (Collector, error)
 type collectorInitList map[string]collectorInitFn
 
 func collectorList() collectorInitList {
@@ -118,8 +124,10 @@ type common struct {
 	tsStart      time.Time
 	tsEnd        time.Time
 	ctx          context.Context
+	stateMu      *sync.Mutex // protects enabled, disableCause, disableTime (status may be requested during collection)
 	disableTime  *time.Time
 	check        *circonus.Check
+	sink         circonus.Sink
 	filter       Filter
 	id           string
 	disableCause string
@@ -128,13 +136,15 @@ type common struct {
 	enabled      bool
 }
 
-func newCommon(ctx context.Context, check *circonus.Check, cfg *GCPCollector, interval time.Duration, logger zerolog.Logger) common {
+func newCommon(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *GCPCollector, interval time.Duration, logger zerolog.Logger) common {
 	return common{
 		id:           cfg.Name,
 		enabled:      true,
+		stateMu:      &sync.Mutex{},
 		disableCause: "",
 		disableTime:  nil,
 		check:        check,
+		sink:         sink,
 		interval:     interval,
 		filter:       cfg.Filter,
 		ctx:          ctx,
@@ -147,6 +157,74 @@ func (c *common) ID() string {
 	return c.id
 }
 
+// Status returns the current state of the collector.
+func (c *common) Status() services.CollectorStatus {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	status := services.CollectorStatus{
+		ID:           c.id,
+		Enabled:      c.enabled,
+		DisableCause: c.disableCause,
+	}
+	if !c.enabled && c.disableTime != nil {
+		dt := *c.disableTime
+		status.DisableTime = &dt
+	}
+
+	return status
+}
+
+// Enabled returns true if the collector is enabled. If the collector has been
+// dynamically disabled by a potentially temporary error, that will be logged
+// and the collector will be re-enabled to try again after an hour.
+func (c *common) Enabled() bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	if c.enabled {
+		return true
+	}
+	if c.disableCause == "" || c.disableTime == nil {
+		return false
+	}
+	if time.Since(*c.disableTime) >= 1*time.Hour {
+		c.enabled = true // RE-enable to try again, since it is configured to be enabled
+		return true
+	}
+
+	c.logger.Warn().
+		Time("disable_time", *c.disableTime).
+		Str("disable_cause", c.disableCause).
+		Msg("collector has been disabled due to error")
+	return false
+}
+
+// recordAPICall tracks a call to a gcp api, and the error code if the
+// call failed, in the check's self-telemetry.
+func (c *common) recordAPICall(err error) {
+	if c.check == nil {
+		return
+	}
+	c.check.RecordAPICall(c.id, gcpErrorCode(err))
+}
+
+// gcpErrorCode returns the http status (rest apis) or grpc status code name
+// (e.g. monitoring api) for err, or an empty string if there was no error.
+func gcpErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.Code)
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code().String()
+	}
+	return "unknown"
+}
+
 func (c *common) done() bool {
 	select {
 	case <-c.ctx.Done():
@@ -6,7 +6,6 @@
 package collectors
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -25,18 +24,21 @@ type Compute struct {
 	common
 }
 
-func newCompute(ctx context.Context, check *circonus.Check, cfg *GCPCollector, interval time.Duration, logger zerolog.Logger) (Collector, error) {
+func newCompute(ctx context.Context, check *circonus.Check, sink circonus.Sink, cfg *GCPCollector, interval time.Duration, logger zerolog.Logger) (Collector, error) {
 	if ctx == nil {
 		return nil, errors.New("invalid context (nil)")
 	}
 	if check == nil {
 		return nil, errors.New("invalid check (nil)")
 	}
+	if sink == nil {
+		return nil, errors.New("invalid sink (nil)")
+	}
 	if cfg == nil {
 		return nil, errors.New("invalid config (nil)")
 	}
 	c := &Compute{
-		common: newCommon(ctx, check, cfg, interval, logger),
+		common: newCommon(ctx, check, sink, cfg, interval, logger),
 	}
 	c.logger.Debug().Msg("initialized")
 	return c, nil
@@ -45,19 +47,8 @@ func newCompute(ctx context.Context, check *circonus.Check, cfg *GCPCollector, i
 // Collect telemetry from gce instance resources in project.
 func (c *Compute) Collect(timeseriesStart, timeseriesEnd time.Time, projectID string, creds []byte, baseTags circonus.Tags) error {
 
-	if !c.enabled {
-		if c.disableCause == "" || c.disableTime == nil {
-			return nil
-		}
-		if time.Since(*c.disableTime) >= 1*time.Hour {
-			c.enabled = true // RE-enable to try again, since it is configured to be enabled
-		} else {
-			c.logger.Warn().
-				Time("disable_time", *c.disableTime).
-				Str("disable_cause", c.disableCause).
-				Msg("collector has been disabled due to error")
-			return nil
-		}
+	if !c.Enabled() {
+		return nil
 	}
 
 	runStart := timeseriesEnd
@@ -74,15 +65,15 @@ func (c *Compute) Collect(timeseriesStart, timeseriesEnd time.Time, projectID st
 		return nil
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(32768)
+	buf := c.sink.NewBatch()
+	defer buf.Discard() // no-op unless abandoned before being submitted
 	c.logger.Debug().Int("instances", len(instanceList)).Msg("processing instances")
 	for _, info := range instanceList {
 		instStart := time.Now()
 		instLogger := c.logger.With().Str("region", info.region).Str("instance", info.name).Logger()
 
 		metricFilter := fmt.Sprintf(`metric.labels.instance_name = "%s"`, info.name)
-		if err := c.processMetrics(projectID, metricFilter, creds, &buf, baseTags); err != nil {
+		if err := c.processMetrics(projectID, metricFilter, creds, buf, baseTags); err != nil {
 			instLogger.Warn().Err(err).Msg("collecting instance metrics")
 		}
 		instLogger.Info().Str("duration", time.Since(instStart).String()).Msg("instance collect end")
@@ -93,13 +84,12 @@ func (c *Compute) Collect(timeseriesStart, timeseriesEnd time.Time, projectID st
 		}
 
 		submitStart := time.Now()
-		if err := c.check.SubmitMetrics(&buf); err != nil {
+		if err := buf.Submit(); err != nil {
 			c.check.ReportError(errors.WithMessage(err, fmt.Sprintf("collector: %s", c.ID())))
 			instLogger.Error().Err(err).Msg("submitting telemetry")
 		}
 		instLogger.Info().Str("duration", time.Since(submitStart).String()).Msg("instance submit end")
 
-		buf.Reset()
 		instLogger.Info().Str("duration", time.Since(instStart).String()).Msg("instance run end")
 
 		if c.done() {
@@ -163,6 +153,7 @@ func (c *Compute) getInstanceList(projectID string, creds []byte) ([]instanceInf
 		// https://godoc.org/google.golang.org/api/compute/v1#InstancesService.AggregatedList
 		iac := isvc.AggregatedList(projectID)
 		ial, err := iac.Context(c.ctx).PageToken(nextPageToken).Filter(filter).Do()
+		c.recordAPICall(err)
 		if err != nil {
 			return instanceList, errors.Wrap(err, "instances aggregated list")
 		}
@@ -7,7 +7,6 @@ package collectors
 
 import (
 	"fmt"
-	"io"
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
@@ -21,7 +20,7 @@ import (
 )
 
 // processMetrics retrieves the available metrics for a resource identified by the supplied filter.
-func (c *common) processMetrics(projectID, filter string, creds []byte, metricDest io.Writer, baseTags circonus.Tags) error {
+func (c *common) processMetrics(projectID, filter string, creds []byte, metricDest circonus.Batch, baseTags circonus.Tags) error {
 	client, err := monitoring.NewMetricClient(c.ctx, option.WithCredentialsJSON(creds))
 	if err != nil {
 		return errors.Wrap(err, "gcp monitoring client")
@@ -36,12 +35,15 @@ func (c *common) processMetrics(projectID, filter string, creds []byte, metricDe
 	// c.logger.Debug().Str("filter", filter).Msg("getting metric descriptors")
 	metricDescriptors := make([]*metric.MetricDescriptor, 0)
 	iter := client.ListMetricDescriptors(c.ctx, req)
+	// NOTE: the iterator fetches pages as needed, api calls are tracked once per list request
 	for {
 		metricDescriptor, err := iter.Next()
 		if errors.Is(err, iterator.Done) {
+			c.recordAPICall(nil)
 			break
 		}
 		if err != nil {
+			c.recordAPICall(err)
 			c.logger.Warn().Err(err).Interface("err_val", err).Str("filter", filter).Msg("metric descriptor, iter.next, skipping remainder")
 			break
 		}
@@ -74,7 +76,7 @@ func (c *common) processMetrics(projectID, filter string, creds []byte, metricDe
 }
 
 // fetchTimeseries retrieves the actual samples for the metric defined by the filter.
-func (c *common) fetchTimeseries(client *monitoring.MetricClient, projectID, filter string, creds []byte, metricName string, metricDest io.Writer, baseTags circonus.Tags) {
+func (c *common) fetchTimeseries(client *monitoring.MetricClient, projectID, filter string, creds []byte, metricName string, metricDest circonus.Batch, baseTags circonus.Tags) {
 	_ = creds // ref to keep signatures same and squelch lint unused warning
 
 	req := &monitoringpb.ListTimeSeriesRequest{
@@ -97,9 +99,11 @@ func (c *common) fetchTimeseries(client *monitoring.MetricClient, projectID, fil
 	for {
 		timeSeries, err := it.Next()
 		if errors.Is(err, iterator.Done) {
+			c.recordAPICall(nil)
 			break
 		}
 		if err != nil {
+			c.recordAPICall(err)
 			c.logger.Warn().Err(err).Interface("err_val", err).Str("filter", filter).Msg("metric timeseries, iter.next, skipping remainder")
 			break
 		}
@@ -165,7 +169,7 @@ func (c *common) fetchTimeseries(client *monitoring.MetricClient, projectID, fil
 				c.logger.Error().Str("name", metricName).Str("type", metricType).Msg("invalid metric type")
 				continue
 			}
-			if err := c.check.WriteMetricSample(metricDest, mn, metricType, value, &ts); err != nil {
+			if err := metricDest.WriteMetricSample(mn, metricType, value, &ts); err != nil {
 				c.logger.Warn().Err(err).Str("name", metricName).Msg("recording metric")
 			}
 		}
@@ -0,0 +1,35 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+)
+
+// Validate returns the problems found with the collector configurations,
+// field paths are relative to the list (e.g. [0].name).
+func Validate(cfgs []GCPCollector) []error {
+	var errs []error
+	cl := collectorList()
+	for i, cfg := range cfgs {
+		field := fmt.Sprintf("[%d]", i)
+		switch {
+		case cfg.Name == "":
+			errs = append(errs, config.FieldErrorf(field+".name", "required"))
+		default:
+			if _, known := cl[strings.ToLower(cfg.Name)]; !known {
+				errs = append(errs, config.FieldErrorf(field+".name", "unrecognized gcp service (%s)", cfg.Name))
+			}
+		}
+		if len(cfg.Filter.Labels) > 0 && cfg.Filter.Expression != "" {
+			errs = append(errs, config.FieldErrorf(field+".filter", "use labels or expression, not both"))
+		}
+	}
+	return errs
+}
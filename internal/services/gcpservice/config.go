@@ -8,6 +8,7 @@ package gcpservice
 import (
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice/collectors"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/sinks"
 )
 
 // Config defines the options for a gcp service instance.
@@ -16,6 +17,7 @@ type Config struct {
 	Circonus circonus.ServiceConfig `json:"circonus" toml:"circonus" yaml:"circonus"` // REQUIRED circonus config: api credentials, check, broker, etc.
 	Tags     circonus.Tags          `json:"tags" toml:"tags" yaml:"tags"`             // global tags, added to all metrics
 	GCP      GCPConfig              `json:"gcp" toml:"gcp" yaml:"gcp"`                // REQUIRED gcp configuration
+	Sinks    []sinks.Config         `json:"sinks" toml:"sinks" yaml:"sinks"`          // additional destinations for metric samples (file, stdout, http)
 }
 
 // GCPConfig holds the gcp specific configuration options.
@@ -14,12 +14,14 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice/collectors"
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
@@ -39,6 +41,11 @@ const (
 	// KeyConfDir defines the gcp configuration directory.
 	KeyConfDir = "gcp.conf_dir"
 
+	// KeyWatchConfDir toggles watching the GCP configuration directory for changes.
+	KeyWatchConfDir = "gcp.watch_conf_dir"
+	// DefaultWatchConfDir defines the default setting.
+	DefaultWatchConfDir = true
+
 	// KeyConfExample shows an example configuration.
 	KeyConfExample = "gcp.config_example"
 )
@@ -53,8 +60,14 @@ type GCPService struct {
 	logger    zerolog.Logger
 	groupCtx  context.Context
 	group     *errgroup.Group
+	confDir   string
 	instances []*Instance
+	scanMu    sync.Mutex
+	sync.Mutex
 	enabled   bool
+	started   bool
+	watch     bool
+	stateless bool // one-off collections which do not use the state file (see NewFromFile)
 }
 
 // New returns a GCP metric collector service.
@@ -69,6 +82,7 @@ func New(ctx context.Context) (*GCPService, error) {
 	g, gctx := errgroup.WithContext(ctx)
 	svc := GCPService{
 		enabled:   viper.GetBool(KeyEnabled),
+		watch:     viper.GetBool(KeyWatchConfDir),
 		group:     g,
 		groupCtx:  gctx,
 		instances: make([]*Instance, 0),
@@ -81,12 +95,12 @@ func New(ctx context.Context) (*GCPService, error) {
 
 	svc.logger.Debug().Msg("enabled, initializing client")
 
-	confDir := viper.GetString(KeyConfDir)
-	if confDir == "" {
-		confDir = DefaultConfDir
+	svc.confDir = viper.GetString(KeyConfDir)
+	if svc.confDir == "" {
+		svc.confDir = DefaultConfDir
 	}
 
-	if err := svc.initInstances(confDir); err != nil {
+	if err := svc.initInstances(svc.confDir); err != nil {
 		return nil, errors.Wrap(err, "initializing telemetry collector(s)")
 	}
 
@@ -98,19 +112,168 @@ func New(ctx context.Context) (*GCPService, error) {
 	return &svc, nil
 }
 
+// NewFromFile returns a GCP service with the instance defined by a single
+// configuration file, for one-off collections (see CollectOnce). The service
+// is enabled regardless of the gcp.enabled setting, and does not watch for
+// configuration changes. The state file (collection watermarks) is only used
+// if resume is true.
+func NewFromFile(ctx context.Context, cfgFile string, resume bool) (*GCPService, error) {
+	if cfgFile == "" {
+		return nil, errors.New("invalid config file (empty)")
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	svc := GCPService{
+		enabled:   true,
+		stateless: !resume,
+		group:     g,
+		groupCtx:  gctx,
+		confDir:   filepath.Dir(cfgFile),
+		logger:    log.With().Str("pkg", "gcp").Logger(),
+	}
+
+	instance, err := svc.instanceFromConfig(cfgFile, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing telemetry collector")
+	}
+	svc.instances = []*Instance{instance}
+
+	return &svc, nil
+}
+
 // Enabled indicates whether the GCP service is enabled.
 func (svc *GCPService) Enabled() bool {
 	return svc.enabled
 }
 
 // Scan checks the service config directory for configurations and loads them.
+// Instances for new configuration files are started, instances whose configuration
+// file was removed are stopped, and instances whose configuration file changed are
+// restarted. Instances with unchanged configurations are not affected.
 func (svc *GCPService) Scan() error {
 	if !svc.enabled {
 		svc.logger.Info().Msg("client disabled, not checking for configurations")
 		return nil
 	}
-	svc.logger.Info().Msg("client checking for configuration(s)")
-	return errors.New("not implemented")
+	svc.logger.Info().Str("conf_dir", svc.confDir).Msg("client checking for configuration(s)")
+
+	// serialize scans (e.g. SIGHUP and config dir watcher), the service lock
+	// is only held while reading/updating the list of instances so that
+	// status requests are not blocked while new instances are initialized
+	svc.scanMu.Lock()
+	defer svc.scanMu.Unlock()
+
+	files, err := services.ConfigFiles(svc.confDir)
+	if err != nil {
+		return errors.Wrap(err, "scanning GCP config dir")
+	}
+
+	svc.Lock()
+	existingInstances := svc.instances
+	svc.Unlock()
+
+	current := make(map[string]*Instance)
+	for _, inst := range existingInstances {
+		current[inst.cfgFile] = inst
+	}
+
+	instances := make([]*Instance, 0, len(existingInstances))
+	created := make([]*Instance, 0)
+	stopped := make([]*Instance, 0)
+	seen := make(map[string]bool)
+
+	for _, cf := range files {
+		seen[cf.Path] = true
+		existing, found := current[cf.Path]
+		if found && existing.cfgHash == cf.Hash {
+			instances = append(instances, existing)
+			continue
+		}
+
+		// create the replacement instance before stopping the existing one, so
+		// that an invalid configuration does not stop a running collection instance
+		instance, err := svc.instanceFromConfig(cf.Path, cf.Hash)
+		if err != nil {
+			svc.logger.Error().Err(err).Str("config_file", cf.Path).Msg("skipping")
+			if found {
+				instances = append(instances, existing)
+			}
+			continue
+		}
+
+		if found {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("config changed, restarting instance")
+			stopped = append(stopped, existing)
+		} else {
+			svc.logger.Info().Str("config_file", cf.Path).Msg("new config, starting instance")
+		}
+
+		instances = append(instances, instance)
+		created = append(created, instance)
+	}
+
+	for cfgFile, existing := range current {
+		if seen[cfgFile] {
+			continue
+		}
+		svc.logger.Info().Str("config_file", cfgFile).Msg("config removed, stopping instance")
+		stopped = append(stopped, existing)
+	}
+
+	for _, inst := range stopped {
+		inst.Stop()
+	}
+
+	svc.Lock()
+	svc.instances = instances
+	if svc.started {
+		for _, inst := range created {
+			svc.group.Go(inst.Start)
+		}
+	}
+	svc.Unlock()
+
+	return nil
+}
+
+// Status returns the current state of each GCP project instance.
+func (svc *GCPService) Status() []services.InstanceStatus {
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	status := make([]services.InstanceStatus, 0, len(instances))
+	for _, inst := range instances {
+		status = append(status, inst.Status())
+	}
+
+	return status
+}
+
+// CollectOnce runs a single collection for each GCP project instance, concurrently,
+// for the time range tr, waits for them to complete and returns the outcome
+// of each.
+func (svc *GCPService) CollectOnce(tr services.TimeRange) []services.CollectionResult {
+	if !svc.enabled {
+		return nil
+	}
+
+	svc.Lock()
+	instances := svc.instances
+	svc.Unlock()
+
+	results := make([]services.CollectionResult, len(instances))
+	var wg sync.WaitGroup
+	for idx, inst := range instances {
+		wg.Add(1)
+		go func(idx int, inst *Instance) {
+			defer wg.Done()
+			results[idx] = inst.CollectOnce(tr)
+		}(idx, inst)
+	}
+	wg.Wait()
+
+	return results
 }
 
 // Start begins collecting metrics from GCP.
@@ -121,16 +284,26 @@ func (svc *GCPService) Start() error {
 	}
 	svc.logger.Info().Msg("client starting")
 
+	svc.Lock()
 	for _, instance := range svc.instances {
 		inst := instance
 		svc.group.Go(inst.Start)
 	}
+	svc.started = true
+	svc.Unlock()
 
-	go func() {
-		if err := svc.group.Wait(); err != nil {
-			svc.logger.Error().Err(err).Msg("waiting for service group")
-		}
-	}()
+	if svc.watch {
+		svc.group.Go(func() error {
+			if err := services.WatchConfigDir(svc.groupCtx, svc.confDir, svc.Scan, svc.logger); err != nil {
+				svc.logger.Error().Err(err).Msg("config changes will require a SIGHUP to be applied")
+			}
+			return nil
+		})
+	}
+
+	// instances may be added/removed by Scan, wait for the service
+	// to be stopped rather than for the current set of instances
+	<-svc.groupCtx.Done()
 
 	return svc.group.Wait()
 }
@@ -140,25 +313,15 @@ func (svc *GCPService) initInstances(confDir string) error {
 		return errors.New("invalid config dir (empty)")
 	}
 
-	entries, err := os.ReadDir(confDir)
+	files, err := services.ConfigFiles(confDir)
 	if err != nil {
 		return errors.Wrap(err, "reading GCP config dir")
 	}
 
-	for _, entry := range entries {
-		entry := entry
-
-		if entry.IsDir() {
-			continue
-		}
-		if !strings.Contains(".json|.toml|.yaml", filepath.Ext(entry.Name())) { //nolint:gocritic
-			continue
-		}
-
-		cfgFile := path.Join(confDir, entry.Name())
-		instance, err := svc.instanceFromConfig(cfgFile)
+	for _, cf := range files {
+		instance, err := svc.instanceFromConfig(cf.Path, cf.Hash)
 		if err != nil {
-			svc.logger.Error().Err(err).Str("config_file", cfgFile).Msg("skipping")
+			svc.logger.Error().Err(err).Str("config_file", cf.Path).Msg("skipping")
 			continue
 		}
 
@@ -172,41 +335,66 @@ func (svc *GCPService) initInstances(confDir string) error {
 	return nil
 }
 
-func (svc *GCPService) instanceFromConfig(cfgFile string) (*Instance, error) {
+// instanceFromConfig creates an Instance from a configuration file. cfgHash
+// identifies the version of the configuration file the instance was created from.
+func (svc *GCPService) instanceFromConfig(cfgFile, cfgHash string) (*Instance, error) {
 	var cfg Config
 	if err := config.LoadConfigFile(cfgFile, &cfg); err != nil {
 		return nil, errors.Wrap(err, "loading config file")
 	}
 
-	if cfg.ID == "" {
-		return nil, errors.New("invalid config ID (empty)")
-	}
-	if strings.Contains(cfg.ID, " ") {
-		return nil, errors.New("invalid config ID (contains spaces)")
+	if err := services.ValidateID(cfg.ID); err != nil {
+		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(svc.groupCtx)
 	instance := &Instance{
-		cfg:    &cfg,
-		ctx:    svc.groupCtx,
-		logger: svc.logger.With().Str("id", cfg.ID).Logger(),
+		cfg:     &cfg,
+		cfgFile: cfgFile,
+		cfgHash: cfgHash,
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  svc.logger.With().Str("id", cfg.ID).Logger(),
+	}
+
+	if !svc.stateless {
+		wm, err := services.ConfiguredWatermarks()
+		if err != nil {
+			instance.logger.Warn().Err(err).Msg("loading collection state, collections will not resume after restart")
+		}
+		instance.watermarks = wm
+	}
+
+	if err := svc.initInstance(instance); err != nil {
+		cancel()
+		services.CloseCheck(instance.check, instance.sink, instance.logger)
+		return nil, err
 	}
 
+	return instance, nil
+}
+
+// initInstance loads the gcp credentials and project meta data, then creates
+// the circonus check and collectors for the instance.
+func (svc *GCPService) initInstance(instance *Instance) error {
+	cfg := instance.cfg
+
 	if cfg.GCP.CredentialsFile == "" {
-		return nil, errors.New("invalid GCP credentials file (empty)")
+		return errors.New("invalid GCP credentials file (empty)")
 	}
 
-	cfgFile, err := config.VerifyFile(instance.cfg.GCP.CredentialsFile)
+	credsFile, err := config.VerifyFile(instance.cfg.GCP.CredentialsFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid GCP credentials file")
+		return errors.Wrap(err, "invalid GCP credentials file")
 	}
-	if !strings.HasPrefix(cfgFile, string(filepath.Separator)) {
-		cfgFile = filepath.Join(defaults.EtcPath, cfgFile)
+	if !strings.HasPrefix(credsFile, string(filepath.Separator)) {
+		credsFile = filepath.Join(defaults.EtcPath, credsFile)
 	}
-	instance.cfg.GCP.CredentialsFile = cfgFile
+	instance.cfg.GCP.CredentialsFile = credsFile
 
 	data, err := os.ReadFile(instance.cfg.GCP.CredentialsFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "loading GCP credentials file")
+		return errors.Wrap(err, "loading GCP credentials file")
 	}
 	instance.cfg.GCP.credentialData = data
 
@@ -214,30 +402,22 @@ func (svc *GCPService) instanceFromConfig(cfgFile string) (*Instance, error) {
 		ProjectID string `json:"project_id"`
 	}
 	if err = json.Unmarshal(data, &v); err != nil {
-		return nil, err
+		return err
 	}
 	instance.cfg.GCP.projectID = v.ProjectID
 
 	if err = instance.loadProjectMeta(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if cfg.GCP.Interval < defaultInterval {
 		cfg.GCP.Interval = defaultInterval
 	}
 
-	checkConfig := &circonus.Config{
-		ID:            "gcp_" + instance.cfg.ID,
-		DisplayName:   fmt.Sprintf("%s %s %s/gcp", instance.cfg.ID, instance.cfg.GCP.projectName, release.NAME),
-		CheckBundleID: cfg.Circonus.CID,
-		APIKey:        cfg.Circonus.Key,
-		APIApp:        cfg.Circonus.App,
-		APIURL:        cfg.Circonus.URL,
-		Debug:         cfg.Circonus.Debug,
-		TraceMetrics:  cfg.Circonus.TraceMetrics,
-		Logger:        instance.logger,
-		Tags:          release.NAME + ":gcp",
-	}
+	checkConfig := services.CheckConfig(cfg.Circonus, instance.logger)
+	checkConfig.ID = "gcp_" + instance.cfg.ID
+	checkConfig.DisplayName = fmt.Sprintf("%s %s %s/gcp", instance.cfg.ID, instance.cfg.GCP.projectName, release.NAME)
+	checkConfig.Tags = release.NAME + ":gcp"
 	if len(instance.cfg.Tags) > 0 { // if top-level tags are configured, add them to check
 		tags := make([]string, len(instance.cfg.Tags))
 		for idx, tag := range instance.cfg.Tags {
@@ -255,19 +435,25 @@ func (svc *GCPService) instanceFromConfig(cfgFile string) (*Instance, error) {
 
 	chk, err := circonus.NewCheck("gcp", checkConfig)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating/retrieving circonus check")
+		return errors.Wrap(err, "creating/retrieving circonus check")
 	}
 	instance.check = chk
 
+	sink, err := services.NewSink(chk, cfg.Sinks, instance.logger)
+	if err != nil {
+		return errors.Wrap(err, "creating metric sinks")
+	}
+	instance.sink = sink
+
 	// initialize collectors
 	pollingInterval := time.Duration(cfg.GCP.Interval) * time.Minute
-	ms, err := collectors.New(instance.ctx, instance.check, cfg.GCP.Collectors, pollingInterval, instance.logger)
+	ms, err := collectors.New(instance.ctx, instance.check, instance.sink, cfg.GCP.Collectors, pollingInterval, instance.logger)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	instance.collectors = ms
 
-	return instance, nil
+	return nil
 }
 
 func showExampleConfig(format string, w io.Writer) error {
@@ -7,11 +7,11 @@ package gcpservice
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
 	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
 	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice/collectors"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -19,13 +19,19 @@ import (
 
 // Instance defines a specific gcp service instance for collecting metrics.
 type Instance struct {
-	logger     zerolog.Logger
-	ctx        context.Context
-	cfg        *Config
-	check      *circonus.Check
-	lastStart  *time.Time
-	collectors []collectors.Collector
-	baseTags   circonus.Tags
+	logger       zerolog.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+	cfg          *Config
+	cfgFile      string
+	cfgHash      string
+	check        *circonus.Check
+	sink         circonus.Sink
+	lastStart    *time.Time
+	lastDuration time.Duration
+	collectors   []collectors.Collector
+	baseTags     circonus.Tags
+	watermarks   *services.Watermarks
 	sync.Mutex
 	running bool
 }
@@ -55,42 +61,137 @@ func (inst *Instance) Start() error {
 				continue
 			}
 
-			// calculate one timeseries range for all requests from collectors
 			start := time.Now()
-			var delta time.Duration
-			if inst.lastStart == nil {
-				delta = interval * 2
-			} else {
-				delta = start.Sub(*inst.lastStart) + 2*time.Minute
-			}
-			tsEnd := start
-			tsStart := tsEnd.Add(-delta)
-			inst.logger.Info().Time("start", tsStart).Time("end", tsEnd).Str("delta", delta.String()).Msg("collection timeseries range")
-
+			tsStart, tsEnd := inst.nextTimeseriesRange(start, services.TimeRange{})
+			resume := inst.lastStart == nil
 			inst.lastStart = &start
 			inst.running = true
 			inst.Unlock()
 
-			go func() {
-				for _, c := range inst.collectors {
-					if err := c.Collect(tsStart, tsEnd, inst.cfg.GCP.projectID, inst.cfg.GCP.credentialData, inst.baseTags); err != nil {
-						inst.check.ReportError(errors.WithMessage(err, fmt.Sprintf("id: %s, collector: %s", inst.cfg.ID, c.ID())))
-						inst.logger.Warn().Err(err).Str("collector", c.ID()).Msg("collecting telemetry")
-						// need to determine which errors from the various
-						// cloud service providers are fatal vs retry vs
-						// wait for next iteration
-					}
-					if inst.done() {
-						break
-					}
-				}
-				inst.Lock()
-				inst.running = false
-				inst.Unlock()
-				inst.logger.Debug().Str("duration", time.Since(start).String()).Msg("collection complete")
-			}()
+			go inst.collect(tsStart, tsEnd, start, resume)
+		}
+	}
+}
+
+// CollectOnce runs a single collection for the time range tr, waits for it
+// to complete, and returns the outcome (see services.Service.CollectOnce).
+func (inst *Instance) CollectOnce(tr services.TimeRange) services.CollectionResult {
+	result := services.CollectionResult{
+		ID:         inst.cfg.ID,
+		ConfigFile: inst.cfgFile,
+		Project:    inst.cfg.GCP.projectID,
+	}
+
+	inst.Lock()
+	if inst.running {
+		inst.Unlock()
+		result.Err = errors.New("collection in progress")
+		return result
+	}
+	start := time.Now()
+	tsStart, tsEnd := inst.nextTimeseriesRange(start, tr)
+	resume := inst.lastStart == nil && tr.Start.IsZero()
+	inst.lastStart = &start
+	inst.running = true
+	inst.Unlock()
+
+	result.Collectors = inst.collect(tsStart, tsEnd, start, resume)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// nextTimeseriesRange calculates one timeseries range for all requests from
+// collectors, for a collection starting at start. A non-zero tr.Start or
+// tr.End overrides the calculated range. The caller must hold the instance lock.
+func (inst *Instance) nextTimeseriesRange(start time.Time, tr services.TimeRange) (time.Time, time.Time) {
+	tsEnd := start
+	if !tr.End.IsZero() {
+		tsEnd = tr.End
+	}
+	var delta time.Duration
+	if inst.lastStart == nil {
+		delta = time.Duration(inst.cfg.GCP.Interval) * time.Minute * 2
+	} else {
+		delta = start.Sub(*inst.lastStart) + 2*time.Minute
+	}
+	if !tr.Start.IsZero() {
+		delta = tsEnd.Sub(tr.Start)
+	}
+	tsStart := tsEnd.Add(-delta)
+	inst.logger.Info().Time("start", tsStart).Time("end", tsEnd).Str("delta", delta.String()).Msg("collection timeseries range")
+
+	return tsStart, tsEnd
+}
+
+// collect runs each collector for the timeseries range (see
+// services.RunCollection), the collection started at start. If resume is true
+// (the first collection since the instance was created), each collector's
+// range starts from its persisted watermark, if any. Returns the outcome of
+// each collector.
+func (inst *Instance) collect(tsStart, tsEnd, start time.Time, resume bool) []services.CollectorResult {
+	cs := make([]services.InstanceCollector, len(inst.collectors))
+	for i, c := range inst.collectors {
+		c := c
+		cs[i] = services.InstanceCollector{
+			ID: c.ID(),
+			Collect: func(start, end time.Time) error {
+				return c.Collect(start, end, inst.cfg.GCP.projectID, inst.cfg.GCP.credentialData, inst.baseTags)
+			},
 		}
 	}
+
+	results, duration := services.RunCollection(services.Collection{
+		Start:        start,
+		RangeStart:   tsStart,
+		RangeEnd:     tsEnd,
+		Check:        inst.check,
+		Watermarks:   inst.watermarks,
+		Stopped:      inst.done,
+		ID:           inst.cfg.ID,
+		WatermarkKey: []string{"gcp", inst.cfg.ID},
+		Logger:       inst.logger,
+		Overlap:      2 * time.Minute,
+		Resume:       resume,
+	}, cs)
+
+	inst.Lock()
+	inst.running = false
+	inst.lastDuration = duration
+	inst.Unlock()
+
+	return results
+}
+
+// Status returns the current state of the instance.
+func (inst *Instance) Status() services.InstanceStatus {
+	inst.Lock()
+	defer inst.Unlock()
+
+	status := services.InstanceStatus{
+		ID:         inst.cfg.ID,
+		ConfigFile: inst.cfgFile,
+		Project:    inst.cfg.GCP.projectID,
+		Running:    inst.running,
+	}
+	if inst.lastStart != nil {
+		ls := *inst.lastStart
+		status.LastStart = &ls
+	}
+	if inst.lastDuration > 0 {
+		status.LastDuration = inst.lastDuration.String()
+	}
+	for _, c := range inst.collectors {
+		status.Collectors = append(status.Collectors, c.Status())
+	}
+
+	return status
+}
+
+// Stop the instance, any collection in progress will be abandoned.
+func (inst *Instance) Stop() {
+	inst.logger.Info().Msg("stopping client")
+	inst.cancel()
+	services.CloseCheck(inst.check, inst.sink, inst.logger)
 }
 
 // done is a utility routine to check the context, returns true if done.
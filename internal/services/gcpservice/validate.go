@@ -0,0 +1,48 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package gcpservice
+
+import (
+	"fmt"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/services/gcpservice/collectors"
+)
+
+// ValidateConfigFile returns the problems found with a GCP instance
+// configuration file: keys which are not options, values of the wrong type,
+// missing required settings and invalid values. No GCP or Circonus API
+// calls are made.
+func ValidateConfigFile(cfgFile string) []error {
+	var cfg Config
+	if err := config.LoadConfigFileStrict(cfgFile, &cfg); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	if err := services.ValidateID(cfg.ID); err != nil {
+		errs = append(errs, config.FieldErrorf("id", "%s", err))
+	}
+
+	if cfg.GCP.CredentialsFile == "" {
+		errs = append(errs, config.FieldErrorf("gcp.credentials_file", "required"))
+	} else if _, err := config.VerifyFile(cfg.GCP.CredentialsFile); err != nil {
+		errs = append(errs, config.FieldErrorf("gcp.credentials_file", "%s", err))
+	}
+	if cfg.GCP.Interval != 0 && cfg.GCP.Interval < defaultInterval {
+		errs = append(errs, config.FieldErrorf("gcp.collect_interval", "invalid interval (%d), minimum is %d minutes", cfg.GCP.Interval, defaultInterval))
+	}
+	errs = append(errs, config.PrefixFieldErrors("gcp.services", collectors.Validate(cfg.GCP.Collectors))...)
+
+	errs = append(errs, config.PrefixFieldErrors("circonus", cfg.Circonus.Validate())...)
+	errs = append(errs, services.ValidateTags("tags", cfg.Tags)...)
+	for i, sc := range cfg.Sinks {
+		errs = append(errs, config.PrefixFieldErrors(fmt.Sprintf("sinks[%d]", i), sc.Validate())...)
+	}
+
+	return errs
+}
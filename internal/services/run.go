@@ -0,0 +1,115 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// InstanceCollector is a collector run by RunCollection. Collect requests the
+// collector's metrics for the time range start through end. Services which
+// do not use collectors (e.g. azure) run a single collector with an empty ID.
+type InstanceCollector struct {
+	Collect func(start, end time.Time) error
+	ID      string
+}
+
+// Collection defines a single collection by a service instance (see RunCollection).
+type Collection struct {
+	Start        time.Time // time the collection started
+	RangeStart   time.Time // start of the time range collected
+	RangeEnd     time.Time // end of the time range collected
+	Check        *circonus.Check
+	Watermarks   *Watermarks
+	Stopped      func() bool // returns true once the instance has been stopped
+	ID           string      // instance configuration id, added to reported errors
+	WatermarkKey []string    // parts identifying the instance (e.g. aws, id, region), the collector id is added
+	Logger       zerolog.Logger
+	Overlap      time.Duration // overlap with the previous range when resuming from a watermark
+	Resume       bool          // first collection since the instance was created, ranges start from the collector watermarks, if any
+}
+
+// RunCollection runs each of the collectors for the collection's time range,
+// then submits the agent stats for the collection. A collector's watermark is
+// updated when it succeeds, errors are reported to the check. If the instance
+// is stopped, the remaining collectors are skipped and no stats are submitted.
+// Returns the outcome of each collector and the duration of the collection.
+func RunCollection(col Collection, collectors []InstanceCollector) ([]CollectorResult, time.Duration) {
+	var stats bytes.Buffer
+	results := make([]CollectorResult, 0, len(collectors))
+	runStats := col.Check.Stats()
+	for _, c := range collectors {
+		logger := col.Logger
+		errContext := "id: " + col.ID
+		if c.ID != "" {
+			logger = logger.With().Str("collector", c.ID).Logger()
+			errContext = fmt.Sprintf("id: %s, collector: %s", col.ID, c.ID)
+		}
+
+		collectorStart := time.Now()
+		collectorStats := col.Check.Stats()
+		start := col.RangeStart
+		key := WatermarkKey(append(append([]string{}, col.WatermarkKey...), c.ID)...)
+		if col.Resume {
+			if wmStart, ok := col.Watermarks.Start(key, col.RangeEnd, col.Overlap); ok {
+				start = wmStart
+				logger.Info().Time("start", start).Time("end", col.RangeEnd).Msg("resuming collection from watermark")
+			}
+		}
+		err := c.Collect(start, col.RangeEnd)
+		delta := col.Check.Stats().Sub(collectorStats)
+		result := CollectorResult{
+			ID:       c.ID,
+			Err:      err,
+			Samples:  delta.SamplesWritten,
+			APICalls: delta.APICalls,
+		}
+		if err != nil {
+			col.Check.ReportError(errors.WithMessage(err, errContext))
+			logger.Warn().Err(err).Msg("collecting telemetry")
+			// need to determine which errors from the various
+			// cloud service providers are fatal vs retry vs
+			// wait for next iteration
+		} else if !col.Stopped() {
+			if err := col.Watermarks.Update(key, col.RangeEnd); err != nil {
+				logger.Warn().Err(err).Msg("saving collection watermark")
+			}
+		}
+		result.Duration = time.Since(collectorStart)
+		results = append(results, result)
+		if c.ID != "" { // a single unnamed collector is the whole collection
+			col.Check.RecordCollection(c.ID, result.Duration)
+			if err := col.Check.WriteStats(&stats, result.Duration, col.Check.Stats().Sub(collectorStats), circonus.Tags{{Category: "collector", Value: c.ID}}); err != nil {
+				logger.Warn().Err(err).Msg("writing collector stats")
+			}
+		}
+		if col.Stopped() {
+			break
+		}
+	}
+
+	duration := time.Since(col.Start)
+	col.Check.RecordCollection("", duration)
+	col.Logger.Info().Str("duration", duration.String()).Msg("collection complete")
+
+	if col.Stopped() {
+		return results, duration
+	}
+	if err := col.Check.WriteStats(&stats, duration, col.Check.Stats().Sub(runStats), nil); err != nil {
+		col.Logger.Warn().Err(err).Msg("writing instance stats")
+	}
+	if err := col.Check.SubmitMetrics(&stats); err != nil {
+		col.Logger.Warn().Err(err).Msg("submitting agent stats")
+	}
+
+	return results, duration
+}
@@ -0,0 +1,103 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+func TestRunCollection(t *testing.T) {
+	t.Log("Testing RunCollection")
+
+	dir := t.TempDir()
+	check, err := circonus.NewCheck("aws", &circonus.Config{ID: "test", DryRun: true, DryRunOutput: filepath.Join(dir, "dry-run.out"), Logger: zerolog.Nop()})
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	wm, err := OpenWatermarks(filepath.Join(dir, "watermarks.json"), time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	mark := end.Add(-20 * time.Minute)
+	if err := wm.Update(WatermarkKey("aws", "test", "us-east-1", "AWS/EC2"), mark); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	var ec2Start time.Time
+	collectors := []InstanceCollector{
+		{ID: "AWS/EC2", Collect: func(start, end time.Time) error {
+			ec2Start = start
+			return nil
+		}},
+		{ID: "AWS/EBS", Collect: func(start, end time.Time) error {
+			return errors.New("access denied")
+		}},
+	}
+	col := Collection{
+		Start:        end,
+		RangeStart:   end.Add(-10 * time.Minute),
+		RangeEnd:     end,
+		Check:        check,
+		Watermarks:   wm,
+		Stopped:      func() bool { return false },
+		ID:           "test",
+		WatermarkKey: []string{"aws", "test", "us-east-1"},
+		Logger:       zerolog.Nop(),
+		Overlap:      time.Minute,
+		Resume:       true,
+	}
+
+	t.Log("\tresume from watermark, collector error")
+	{
+		results, _ := RunCollection(col, collectors)
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %+v", results)
+		}
+		if results[0].Err != nil || results[1].Err == nil {
+			t.Fatalf("expected AWS/EBS to fail, got %+v", results)
+		}
+		if !ec2Start.Equal(mark.Add(-time.Minute)) {
+			t.Fatalf("expected resume from watermark (%s), got (%s)", mark.Add(-time.Minute), ec2Start)
+		}
+		if start, ok := wm.Start(WatermarkKey("aws", "test", "us-east-1", "AWS/EC2"), end.Add(time.Minute), 0); !ok || !start.Equal(end) {
+			t.Fatalf("expected AWS/EC2 watermark updated, got (%s)", start)
+		}
+		if _, ok := wm.Start(WatermarkKey("aws", "test", "us-east-1", "AWS/EBS"), end.Add(time.Minute), 0); ok {
+			t.Fatal("expected no AWS/EBS watermark")
+		}
+	}
+
+	t.Log("\tno watermarks")
+	{
+		col := col
+		col.Watermarks = nil
+		results, _ := RunCollection(col, collectors)
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %+v", results)
+		}
+		if !ec2Start.Equal(col.RangeStart) {
+			t.Fatalf("expected range start (%s), got (%s)", col.RangeStart, ec2Start)
+		}
+	}
+
+	t.Log("\tstopped")
+	{
+		col := col
+		col.Stopped = func() bool { return true }
+		results, _ := RunCollection(col, collectors)
+		if len(results) != 1 {
+			t.Fatalf("expected 1 result, got %+v", results)
+		}
+	}
+}
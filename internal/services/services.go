@@ -9,4 +9,6 @@ type Service interface {
 	Enabled() bool
 	Scan() error
 	Start() error
+	Status() []InstanceStatus
+	CollectOnce(tr TimeRange) []CollectionResult
 }
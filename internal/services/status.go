@@ -0,0 +1,32 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"time"
+)
+
+// InstanceStatus defines the state of a service collection instance
+// (an AWS region, an Azure subscription, or a GCP project).
+type InstanceStatus struct {
+	LastStart    *time.Time        `json:"last_start"`              // start of the most recent collection, nil if none yet
+	ID           string            `json:"id"`                      // instance configuration id
+	ConfigFile   string            `json:"config_file"`             // configuration file the instance was created from
+	Region       string            `json:"region,omitempty"`        // aws only
+	Subscription string            `json:"subscription,omitempty"`  // azure only
+	Project      string            `json:"project,omitempty"`       // gcp only
+	LastDuration string            `json:"last_duration,omitempty"` // duration of the most recent completed collection
+	Collectors   []CollectorStatus `json:"collectors,omitempty"`    // per collector state (azure does not use collectors)
+	Running      bool              `json:"running"`                 // collection currently in progress
+}
+
+// CollectorStatus defines the state of an individual collector within an instance.
+type CollectorStatus struct {
+	DisableTime  *time.Time `json:"disable_time,omitempty"`  // when the collector was dynamically disabled
+	ID           string     `json:"id"`                      // collector id (e.g. AWS/EC2 or compute)
+	DisableCause string     `json:"disable_cause,omitempty"` // error which caused the collector to be dynamically disabled
+	Enabled      bool       `json:"enabled"`
+}
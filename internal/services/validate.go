@@ -0,0 +1,39 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/pkg/errors"
+)
+
+// ValidateID verifies an instance configuration id, it is required and
+// cannot contain spaces (it is used in check ids, tags, logging, etc.).
+func ValidateID(id string) error {
+	if id == "" {
+		return errors.New("invalid config ID (empty)")
+	}
+	if strings.Contains(id, " ") {
+		return errors.New("invalid config ID (contains spaces)")
+	}
+	return nil
+}
+
+// ValidateTags returns the problems found with a list of tags at field,
+// every tag requires a category.
+func ValidateTags(field string, tags circonus.Tags) []error {
+	var errs []error
+	for i, tag := range tags {
+		if tag.Category == "" {
+			errs = append(errs, config.FieldErrorf(fmt.Sprintf("%s[%d].category", field, i), "required"))
+		}
+	}
+	return errs
+}
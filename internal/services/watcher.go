@@ -0,0 +1,80 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// WatchDelay is how long a config directory must be free of changes before
+// it is rescanned. Editors often write a file in several steps (truncate,
+// write, rename, chmod), waiting for changes to settle avoids loading a
+// partially written configuration file.
+var WatchDelay = 3 * time.Second
+
+// WatchConfigDir watches confDir for changes to configuration files and calls
+// scan once changes have settled. Intended to be run in a goroutine, it runs
+// until the context is done.
+func WatchConfigDir(ctx context.Context, confDir string, scan func() error, logger zerolog.Logger) error {
+	if confDir == "" {
+		return errors.New("invalid config dir (empty)")
+	}
+	if scan == nil {
+		return errors.New("invalid scan func (nil)")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "creating config dir watcher")
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(confDir); err != nil {
+		return errors.Wrapf(err, "watching config dir (%s)", confDir)
+	}
+
+	logger.Info().Str("conf_dir", confDir).Str("delay", WatchDelay.String()).Msg("watching for config changes")
+
+	timer := time.NewTimer(WatchDelay)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue // permission/attribute changes only
+			}
+			if !IsConfigFile(filepath.Base(event.Name)) {
+				continue // e.g. editor swap/backup/temp files
+			}
+			logger.Debug().Str("file", event.Name).Str("op", event.Op.String()).Msg("config change detected")
+			timer.Reset(WatchDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn().Err(err).Str("conf_dir", confDir).Msg("config dir watcher")
+		case <-timer.C:
+			if err := scan(); err != nil {
+				logger.Error().Err(err).Str("conf_dir", confDir).Msg("applying config changes")
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWatchConfigDir(t *testing.T) {
+	t.Log("Testing WatchConfigDir")
+
+	logger := zerolog.Nop()
+
+	t.Log("invalid (empty dir)")
+	{
+		err := WatchConfigDir(context.Background(), "", func() error { return nil }, logger)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid (nil scan)")
+	{
+		err := WatchConfigDir(context.Background(), t.TempDir(), nil, logger)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	WatchDelay = 200 * time.Millisecond
+
+	dir := t.TempDir()
+	scans := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- WatchConfigDir(ctx, dir, func() error {
+			scans <- struct{}{}
+			return nil
+		}, logger)
+	}()
+
+	time.Sleep(100 * time.Millisecond) // allow watcher to start
+
+	t.Log("ignored files")
+	{
+		for _, name := range []string{".#a.yaml", "a.yaml.swp", "a.yaml~"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+				t.Fatalf("writing test file (%s)", err)
+			}
+		}
+		select {
+		case <-scans:
+			t.Fatal("expected no scan")
+		case <-time.After(2 * WatchDelay):
+		}
+	}
+
+	t.Log("debounced changes")
+	{
+		cfgFile := filepath.Join(dir, "a.yaml")
+		for i := 0; i < 5; i++ {
+			if err := os.WriteFile(cfgFile, []byte("id: a"), 0o600); err != nil {
+				t.Fatalf("writing test file (%s)", err)
+			}
+			time.Sleep(WatchDelay / 4)
+		}
+		select {
+		case <-scans:
+		case <-time.After(5 * WatchDelay):
+			t.Fatal("expected scan")
+		}
+		select {
+		case <-scans:
+			t.Fatal("expected only one scan")
+		case <-time.After(2 * WatchDelay):
+		}
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+}
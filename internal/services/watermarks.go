@@ -0,0 +1,175 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Watermarks persists, per instance collector, the end of the time range of
+// the last successful collection in a small state file, so that after a
+// restart collections resume where they stopped rather than using a fixed
+// lookback. Methods on a nil *Watermarks are no-ops (state disabled).
+type Watermarks struct {
+	marks       map[string]time.Time
+	path        string
+	maxLookback time.Duration
+	sync.Mutex
+}
+
+var (
+	watermarksMu    sync.Mutex
+	watermarksFiles = map[string]*Watermarks{}
+)
+
+// ConfiguredWatermarks returns the watermarks for the configured state file
+// (see config.KeyStateFile), nil if the state file is disabled or this is a
+// dry run.
+func ConfiguredWatermarks() (*Watermarks, error) {
+	if viper.GetBool(config.KeyDryRun) {
+		return nil, nil
+	}
+	maxLookback, err := time.ParseDuration(viper.GetString(config.KeyStateMaxLookback))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing state max lookback")
+	}
+	return OpenWatermarks(viper.GetString(config.KeyStateFile), maxLookback)
+}
+
+// OpenWatermarks returns the watermarks persisted in path, shared by all
+// callers using the same path. A resumed time range never starts more than
+// maxLookback before its end. Returns nil if path is empty.
+func OpenWatermarks(path string, maxLookback time.Duration) (*Watermarks, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if maxLookback <= 0 {
+		return nil, errors.Errorf("invalid max lookback (%s)", maxLookback)
+	}
+
+	watermarksMu.Lock()
+	defer watermarksMu.Unlock()
+
+	if w, ok := watermarksFiles[path]; ok {
+		w.Lock()
+		w.maxLookback = maxLookback
+		w.Unlock()
+		return w, nil
+	}
+
+	w := &Watermarks{
+		marks:       make(map[string]time.Time),
+		path:        path,
+		maxLookback: maxLookback,
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "reading state file")
+	default:
+		if err := json.Unmarshal(data, &w.marks); err != nil {
+			return nil, errors.Wrapf(err, "parsing state file (%s)", path)
+		}
+	}
+
+	watermarksFiles[path] = w
+	return w, nil
+}
+
+// WatermarkKey returns the watermark key for the parts identifying an
+// instance collector (e.g. aws, id, region, collector), empty parts are omitted.
+func WatermarkKey(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
+
+// Start returns the start of the time range, ending at end, which resumes
+// collection from the watermark for key. The range overlaps the previous one
+// by overlap (for data which arrives late) and starts no more than the max
+// lookback before end. Returns false if there is no watermark for key, or it
+// is not before end.
+func (w *Watermarks) Start(key string, end time.Time, overlap time.Duration) (time.Time, bool) {
+	if w == nil {
+		return time.Time{}, false
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	mark, ok := w.marks[key]
+	if !ok || !mark.Before(end) {
+		return time.Time{}, false
+	}
+	start := mark.Add(-overlap)
+	if end.Sub(start) > w.maxLookback {
+		start = end.Add(-w.maxLookback)
+	}
+	return start, true
+}
+
+// Update advances the watermark for key to end, the end of a successful
+// collection, and writes the state file. The watermark never moves back
+// (e.g. a backfill of an older time range).
+func (w *Watermarks) Update(key string, end time.Time) error {
+	if w == nil {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if mark, ok := w.marks[key]; ok && !end.After(mark) {
+		return nil
+	}
+	w.marks[key] = end.UTC()
+
+	// merge watermarks written by other processes using the same state file
+	// (e.g. collect-once while the agent is running)
+	if data, err := os.ReadFile(w.path); err == nil {
+		var current map[string]time.Time
+		if json.Unmarshal(data, &current) == nil {
+			for k, t := range current {
+				if t.After(w.marks[k]) {
+					w.marks[k] = t
+				}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(w.marks, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding state")
+	}
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o700); err != nil {
+		return errors.Wrap(err, "creating state dir")
+	}
+	// write a temporary file and rename it, so an interrupted write does not
+	// leave a truncated state file
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return errors.Wrap(err, "writing state file")
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return errors.Wrap(err, "replacing state file")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatermarks(t *testing.T) {
+	t.Log("Testing Watermarks")
+
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	key := WatermarkKey("aws", "prod", "us-east-1", "", "AWS/EC2")
+	if key != "aws/prod/us-east-1/AWS/EC2" {
+		t.Fatalf("unexpected key (%s)", key)
+	}
+
+	t.Log("\tdisabled")
+	{
+		w, err := OpenWatermarks("", time.Hour)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if _, ok := w.Start(key, end, time.Minute); ok {
+			t.Fatal("expected no watermark")
+		}
+		if err := w.Update(key, end); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("\tpersisted")
+	{
+		file := filepath.Join(t.TempDir(), "state", "watermarks.json")
+		w, err := OpenWatermarks(file, time.Hour)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if _, ok := w.Start(key, end, time.Minute); ok {
+			t.Fatal("expected no watermark")
+		}
+		mark := end.Add(-10 * time.Minute)
+		if err := w.Update(key, mark); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := w.Update(key, mark.Add(-time.Hour)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		// simulate a restart
+		watermarksMu.Lock()
+		delete(watermarksFiles, file)
+		watermarksMu.Unlock()
+
+		w, err = OpenWatermarks(file, time.Hour)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		start, ok := w.Start(key, end, time.Minute)
+		if !ok {
+			t.Fatal("expected watermark")
+		}
+		if !start.Equal(mark.Add(-time.Minute)) {
+			t.Fatalf("expected start %s (watermark not moved back, less overlap), got %s", mark.Add(-time.Minute), start)
+		}
+
+		start, ok = w.Start(key, end.Add(3*time.Hour), time.Minute)
+		if !ok {
+			t.Fatal("expected watermark")
+		}
+		if !start.Equal(end.Add(2 * time.Hour)) {
+			t.Fatalf("expected start capped at max lookback, got %s", start)
+		}
+
+		if _, ok := w.Start(key, mark, time.Minute); ok {
+			t.Fatal("expected no start for end at watermark")
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// dedupExpire is how long a series is tracked after its last sample,
+	// longer than any collection overlap.
+	dedupExpire = 6 * time.Hour
+	// dedupPruneInterval is how often series which have expired are removed.
+	dedupPruneInterval = 15 * time.Minute
+)
+
+// dedup skips samples which were already delivered, tracking the last
+// timestamp delivered for each series (metric name including stream tags).
+type dedup struct {
+	next      circonus.Sink
+	last      map[string]int64 // series -> last timestamp delivered, milliseconds
+	lastPrune time.Time
+	window    int64 // revision window, milliseconds
+	logger    zerolog.Logger
+	sync.Mutex
+}
+
+// Dedup returns a sink which delivers batches to next, skipping samples for
+// a series (metric name including stream tags) with a timestamp at or
+// before the last one delivered. Collections request overlapping time
+// ranges (e.g. aws adds the interval, gcp two minutes) so consecutive
+// collections return many of the same samples. Samples within window of the
+// last timestamp delivered are delivered again, so values revised by late
+// arriving data are updated. Samples without a timestamp are always
+// delivered. Series are tracked in memory, after a restart the overlap is
+// delivered again.
+func Dedup(next circonus.Sink, window time.Duration, logger zerolog.Logger) circonus.Sink {
+	if window < 0 {
+		window = 0
+	}
+	return &dedup{
+		next:      next,
+		last:      make(map[string]int64),
+		lastPrune: time.Now(),
+		window:    window.Milliseconds(),
+		logger:    logger.With().Str("pkg", "sinks").Logger(),
+	}
+}
+
+func (d *dedup) NewBatch() circonus.Batch {
+	return &dedupBatch{
+		dedup:   d,
+		next:    d.next.NewBatch(),
+		pending: make(map[string]int64),
+	}
+}
+
+// Close closes the next sink.
+func (d *dedup) Close() error {
+	return Close(d.next)
+}
+
+// skip returns true if a sample for series with timestamp ts (milliseconds)
+// was already delivered, taking samples pending in the batch into account.
+func (d *dedup) skip(series string, ts int64, pending map[string]int64) bool {
+	d.Lock()
+	last, ok := d.last[series]
+	d.Unlock()
+	if p, found := pending[series]; found && (!ok || p > last) {
+		last, ok = p, true
+	}
+	return ok && ts <= last-d.window
+}
+
+// commit records the series delivered in a batch.
+func (d *dedup) commit(pending map[string]int64) {
+	d.Lock()
+	defer d.Unlock()
+
+	for series, ts := range pending {
+		if ts > d.last[series] {
+			d.last[series] = ts
+		}
+	}
+
+	if time.Since(d.lastPrune) < dedupPruneInterval {
+		return
+	}
+	expire := time.Now().Add(-dedupExpire).UnixMilli()
+	for series, ts := range d.last {
+		if ts < expire {
+			delete(d.last, series)
+		}
+	}
+	d.lastPrune = time.Now()
+}
+
+type dedupBatch struct {
+	dedup   *dedup
+	next    circonus.Batch
+	pending map[string]int64
+	skipped int
+}
+
+// track returns false if the sample should be skipped, otherwise it is
+// recorded as pending delivery.
+func (b *dedupBatch) track(metricName string, timestamp *time.Time) bool {
+	if timestamp == nil {
+		return true
+	}
+	ts := timestamp.UnixMilli()
+	if b.dedup.skip(metricName, ts, b.pending) {
+		b.skipped++
+		return false
+	}
+	if ts > b.pending[metricName] {
+		b.pending[metricName] = ts
+	}
+	return true
+}
+
+func (b *dedupBatch) WriteMetricSample(metricName, metricType string, value interface{}, timestamp *time.Time) error {
+	if !b.track(metricName, timestamp) {
+		return nil
+	}
+	return b.next.WriteMetricSample(metricName, metricType, value, timestamp)
+}
+
+func (b *dedupBatch) WriteCounterSample(metricName string, value float64, timestamp *time.Time) error {
+	if !b.track(metricName, timestamp) {
+		return nil
+	}
+	return circonus.WriteCounterSample(b.next, metricName, value, timestamp)
+}
+
+func (b *dedupBatch) Len() int {
+	return b.next.Len()
+}
+
+// Submit delivers the batch, the series are only recorded as delivered if
+// it succeeds (including a failed submission spooled for replay), so samples
+// from a failed submission are delivered again by the next collection.
+func (b *dedupBatch) Submit() error {
+	if b.skipped > 0 {
+		b.dedup.logger.Debug().Int("skipped", b.skipped).Msg("duplicate samples skipped")
+	}
+	pending := b.pending
+	b.reset()
+	if err := b.next.Submit(); err != nil {
+		return err
+	}
+	b.dedup.commit(pending)
+	return nil
+}
+
+func (b *dedupBatch) Discard() {
+	b.reset()
+	b.next.Discard()
+}
+
+func (b *dedupBatch) reset() {
+	b.pending = make(map[string]int64)
+	b.skipped = 0
+}
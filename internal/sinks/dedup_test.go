@@ -0,0 +1,112 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+func TestDedup(t *testing.T) {
+	t.Log("Testing Dedup")
+
+	base := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	write := func(t *testing.T, s circonus.Sink, name string, minutes ...int) {
+		t.Helper()
+		b := s.NewBatch()
+		for _, m := range minutes {
+			ts := base.Add(time.Duration(m) * time.Minute)
+			if err := b.WriteMetricSample(name, "n", m, &ts); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+		if err := circonus.WriteCounterSample(b, "requests", 1, nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := b.Submit(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+	values := func(samples []Sample, name string) []int {
+		var v []int
+		for _, s := range samples {
+			if n, ok := s.Value.(int); ok && s.Name == name {
+				v = append(v, n)
+			}
+		}
+		return v
+	}
+
+	t.Log("\toverlapping collections")
+	{
+		mem := &memSink{}
+		s := Dedup(mem, 0, zerolog.Nop())
+		write(t, s, "cpu", 0, 1, 2)
+		write(t, s, "cpu", 1, 2, 3, 4)
+		write(t, s, "mem", 1, 2)
+		if got := values(mem.batches[1], "cpu"); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+			t.Fatalf("expected only new samples 3,4 got %v", got)
+		}
+		if got := values(mem.batches[2], "mem"); len(got) != 2 {
+			t.Fatalf("expected series tracked separately, got %v", got)
+		}
+		if len(mem.batches[1]) != 3 || mem.batches[1][2].Name != "requests" {
+			t.Fatalf("expected sample without timestamp delivered, got %v", mem.batches[1])
+		}
+	}
+
+	t.Log("\trevision window")
+	{
+		mem := &memSink{}
+		s := Dedup(mem, 2*time.Minute, zerolog.Nop())
+		write(t, s, "cpu", 0, 1, 2, 3)
+		write(t, s, "cpu", 0, 1, 2, 3, 4)
+		if got := values(mem.batches[1], "cpu"); len(got) != 3 || got[0] != 2 {
+			t.Fatalf("expected samples within window delivered again (2,3,4), got %v", got)
+		}
+	}
+
+	t.Log("\tdiscarded batch")
+	{
+		mem := &memSink{}
+		s := Dedup(mem, 0, zerolog.Nop())
+		b := s.NewBatch()
+		ts := base
+		if err := b.WriteMetricSample("cpu", "n", 0, &ts); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		b.Discard()
+		write(t, s, "cpu", 0)
+		if got := values(mem.batches[0], "cpu"); len(got) != 1 {
+			t.Fatalf("expected discarded sample not tracked, got %v", got)
+		}
+	}
+
+	t.Log("\tfailed submission")
+	{
+		mem := &memSink{err: errors.New("submit failed")}
+		s := Dedup(mem, 0, zerolog.Nop())
+		b := s.NewBatch()
+		for _, m := range []int{0, 1} {
+			ts := base.Add(time.Duration(m) * time.Minute)
+			if err := b.WriteMetricSample("cpu", "n", m, &ts); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+		if err := b.Submit(); err == nil {
+			t.Fatal("expected error")
+		}
+		mem.err = nil
+		write(t, s, "cpu", 0, 1, 2)
+		if got := values(mem.batches[0], "cpu"); len(got) != 3 {
+			t.Fatalf("expected failed samples delivered again (0,1,2), got %v", got)
+		}
+	}
+}
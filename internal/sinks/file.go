@@ -0,0 +1,80 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/pkg/errors"
+)
+
+// lineWriter writes batches of samples, one JSON document per line. Each
+// batch is written with a single write so that batches from instances
+// sharing the destination are not interleaved.
+type lineWriter struct {
+	w io.Writer
+	sync.Mutex
+}
+
+func (l *lineWriter) NewBatch() circonus.Batch {
+	return &jsonBatch{deliver: l.write}
+}
+
+func (l *lineWriter) write(samples []Sample) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return errors.Wrap(err, "encoding sample")
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if _, err := l.w.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "writing samples")
+	}
+	return nil
+}
+
+var (
+	filesMu sync.Mutex
+	files   = map[string]*lineWriter{}
+	stdout  = &lineWriter{w: os.Stdout}
+)
+
+// NewFile returns a sink appending samples, one JSON document per line, to
+// the file at path. Instances configured with the same path share the file.
+func NewFile(path string) (circonus.Sink, error) {
+	if path == "" {
+		return nil, errors.New("invalid path (empty)")
+	}
+
+	filesMu.Lock()
+	defer filesMu.Unlock()
+
+	if lw, ok := files[path]; ok {
+		return lw, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening sink file")
+	}
+	lw := &lineWriter{w: f}
+	files[path] = lw
+	return lw, nil
+}
+
+// NewStdout returns a sink writing samples, one JSON document per line, to stdout.
+func NewStdout() circonus.Sink {
+	return stdout
+}
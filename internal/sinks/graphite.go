@@ -0,0 +1,306 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// GraphiteFormatPath flattens tags into dotted metric paths (e.g. prefix.us-east-1.i-123.CPUUtilization.Average).
+	GraphiteFormatPath = "path"
+	// GraphiteFormatTagged uses the Graphite tagged series syntax (e.g. prefix.CPUUtilization.Average;aws_region=us-east-1).
+	GraphiteFormatTagged = "tagged"
+
+	// maxGraphiteDatagram is the maximum size of a udp datagram, batches are split on line boundaries.
+	maxGraphiteDatagram = 1400
+)
+
+// graphiteSink sends each batch of samples to a Graphite (carbon) plaintext
+// protocol listener.
+type graphiteSink struct {
+	conn   *graphiteConn
+	prefix string
+	tagged bool
+	logger zerolog.Logger
+}
+
+// graphiteConn is a connection to a carbon listener, shared by the sinks
+// sending to the same address. It is (re)established when needed.
+type graphiteConn struct {
+	conn    net.Conn
+	network string
+	addr    string
+	timeout time.Duration
+	sync.Mutex
+}
+
+var (
+	graphiteConnsMu sync.Mutex
+	graphiteConns   = map[string]*graphiteConn{}
+)
+
+// NewGraphite returns a sink which sends each batch of samples to a Graphite
+// plaintext protocol listener at destURL (tcp://host:port or udp://host:port).
+// Each sample is one line with the sample timestamp (from the cloud service,
+// not the time of receipt) so data points land where they were measured.
+// See graphitePath and graphiteTaggedName for how metric names and tags are
+// mapped. Text samples are skipped.
+func NewGraphite(destURL, format, prefix string, timeout time.Duration, logger zerolog.Logger) (circonus.Sink, error) {
+	if destURL == "" {
+		return nil, errors.New("invalid url (empty)")
+	}
+	u, err := url.Parse(destURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+	network := strings.ToLower(u.Scheme)
+	if network != "tcp" && network != "udp" {
+		return nil, errors.Errorf("invalid url scheme (%s), tcp or udp", u.Scheme)
+	}
+	if u.Host == "" || u.Port() == "" {
+		return nil, errors.Errorf("invalid url (%s), host:port required", destURL)
+	}
+
+	var tagged bool
+	switch strings.ToLower(format) {
+	case "", GraphiteFormatPath:
+	case GraphiteFormatTagged:
+		tagged = true
+	default:
+		return nil, errors.Errorf("unknown graphite format (%s)", format)
+	}
+
+	key := network + "://" + u.Host
+	graphiteConnsMu.Lock()
+	gc, ok := graphiteConns[key]
+	if !ok {
+		gc = &graphiteConn{network: network, addr: u.Host, timeout: timeout}
+		graphiteConns[key] = gc
+	}
+	graphiteConnsMu.Unlock()
+
+	return &graphiteSink{
+		conn:   gc,
+		prefix: strings.Trim(prefix, "."),
+		tagged: tagged,
+		logger: logger.With().Str("pkg", "sinks").Str("graphite", key).Logger(),
+	}, nil
+}
+
+func (g *graphiteSink) NewBatch() circonus.Batch {
+	return &jsonBatch{deliver: g.send}
+}
+
+func (g *graphiteSink) send(samples []Sample) error {
+	data := g.encode(samples)
+	if len(data) == 0 {
+		return nil // nothing graphite can represent
+	}
+	if err := g.conn.write(data); err != nil {
+		return errors.Wrap(err, "sending samples")
+	}
+	g.logger.Debug().Int("samples", len(samples)).Msg("samples sent")
+	return nil
+}
+
+// encode returns the plaintext protocol lines (<path> <value> <timestamp>) for samples.
+func (g *graphiteSink) encode(samples []Sample) []byte {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		if s.Type == circonus.MetricTypeString {
+			continue
+		}
+		v, ok := floatValue(s.Value)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if g.tagged {
+			buf.WriteString(graphiteTaggedName(g.prefix, s))
+		} else {
+			buf.WriteString(graphitePath(g.prefix, s))
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(s.Timestamp/1000, 10))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// write sends data, a set of complete lines. Over tcp, a failed write is
+// retried once on a new connection (e.g. carbon closed an idle connection).
+// Over udp, data is split into datagrams on line boundaries.
+func (gc *graphiteConn) write(data []byte) error {
+	gc.Lock()
+	defer gc.Unlock()
+
+	if gc.network == "udp" {
+		if err := gc.connect(); err != nil {
+			return err
+		}
+		for len(data) > 0 {
+			n := len(data)
+			if n > maxGraphiteDatagram {
+				if i := bytes.LastIndexByte(data[:maxGraphiteDatagram], '\n'); i > 0 {
+					n = i + 1
+				} else if i := bytes.IndexByte(data, '\n'); i > 0 {
+					n = i + 1 // single line longer than the datagram limit
+				}
+			}
+			if _, err := gc.conn.Write(data[:n]); err != nil {
+				gc.close()
+				return errors.Wrap(err, "writing datagram")
+			}
+			data = data[n:]
+		}
+		return nil
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = gc.connect(); err != nil {
+			return err
+		}
+		if _, err = gc.conn.Write(data); err == nil {
+			return nil
+		}
+		gc.close()
+	}
+	return errors.Wrap(err, "writing lines")
+}
+
+func (gc *graphiteConn) connect() error {
+	if gc.conn == nil {
+		conn, err := net.DialTimeout(gc.network, gc.addr, gc.timeout)
+		if err != nil {
+			return errors.Wrap(err, "connecting")
+		}
+		gc.conn = conn
+	}
+	if gc.timeout > 0 {
+		if err := gc.conn.SetWriteDeadline(time.Now().Add(gc.timeout)); err != nil {
+			gc.close()
+			return errors.Wrap(err, "setting write deadline")
+		}
+	}
+	return nil
+}
+
+func (gc *graphiteConn) close() {
+	if gc.conn != nil {
+		_ = gc.conn.Close()
+		gc.conn = nil
+	}
+}
+
+// graphitePath flattens a sample into a dotted Graphite path: the prefix, the
+// tag values ordered by tag category, then the metric name with the stat
+// suffix and any slashes as path nodes. E.g. CPUUtilization`Average tagged
+// aws_region:us-east-1,instance-id:i-123 becomes
+// prefix.us-east-1.i-123.CPUUtilization.Average.
+func graphitePath(prefix string, s Sample) string {
+	nodes := make([]string, 0, len(s.Tags)+3)
+	if prefix != "" {
+		nodes = append(nodes, prefix)
+	}
+	for _, cat := range sortedTagCategories(s.Tags) {
+		if node := graphiteNode(s.Tags[cat]); node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	nodes = append(nodes, graphiteMetricName(s.Name))
+	return strings.Join(nodes, ".")
+}
+
+// graphiteTaggedName returns a Graphite tagged series name, the prefix and
+// metric name followed by ;category=value for each tag. A category of name,
+// which Graphite reserves, is prefixed with tag_.
+func graphiteTaggedName(prefix string, s Sample) string {
+	var sb strings.Builder
+	if prefix != "" {
+		sb.WriteString(prefix)
+		sb.WriteByte('.')
+	}
+	sb.WriteString(graphiteMetricName(s.Name))
+	for _, cat := range sortedTagCategories(s.Tags) {
+		name := graphiteTagName(cat)
+		val := graphiteTagValue(s.Tags[cat])
+		if name == "" || val == "" {
+			continue
+		}
+		sb.WriteByte(';')
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(val)
+	}
+	return sb.String()
+}
+
+// graphiteMetricName maps a metric name to dotted path nodes, the metric
+// name separator (e.g. the backtick in CPUUtilization`Average) and slashes
+// (e.g. gcp compute.googleapis.com/instance/cpu/utilization) separate nodes.
+func graphiteMetricName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '`' || r == '/' || r == '.' })
+	nodes := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if node := graphiteNode(p); node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	return strings.Join(nodes, ".")
+}
+
+// graphiteNode returns s as a single path node, characters other than
+// letters, digits, - and _ are replaced with underscores.
+func graphiteNode(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func graphiteTagName(category string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == ';' || r == '!' || r == '^' || r == '=' || r == '~' || r <= ' ' {
+			return '_'
+		}
+		return r
+	}, category)
+	if name == "name" {
+		name = "tag_name"
+	}
+	return name
+}
+
+func graphiteTagValue(value string) string {
+	val := strings.Map(func(r rune) rune {
+		if r == ';' || r <= ' ' {
+			return '_'
+		}
+		return r
+	}, value)
+	if strings.HasPrefix(val, "~") {
+		val = "_" + val[1:]
+	}
+	return val
+}
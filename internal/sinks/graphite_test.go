@@ -0,0 +1,150 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+func TestGraphiteNames(t *testing.T) {
+	t.Log("Testing graphite names")
+
+	s := Sample{
+		Name: "CPUUtilization`Average",
+		Tags: map[string]string{"instance-id": "i-123", "aws_region": "us-east-1", "name": "web server;1"},
+	}
+
+	t.Log("\tpath")
+	{
+		expect := "cca.us-east-1.i-123.web_server_1.CPUUtilization.Average"
+		if got := graphitePath("cca", s); got != expect {
+			t.Fatalf("expected %s, got %s", expect, got)
+		}
+		expect = "compute.googleapis.com.instance.cpu.utilization"
+		if got := graphitePath("", Sample{Name: "compute.googleapis.com/instance/cpu/utilization"}); got != expect {
+			t.Fatalf("expected %s, got %s", expect, got)
+		}
+	}
+
+	t.Log("\ttagged")
+	{
+		expect := "cca.CPUUtilization.Average;aws_region=us-east-1;instance-id=i-123;tag_name=web_server_1"
+		if got := graphiteTaggedName("cca", s); got != expect {
+			t.Fatalf("expected %s, got %s", expect, got)
+		}
+	}
+}
+
+func TestGraphite(t *testing.T) {
+	t.Log("Testing graphite sink")
+
+	t.Log("\tinvalid url")
+	{
+		for _, u := range []string{"", "http://localhost:2003", "tcp://localhost"} {
+			if _, err := NewGraphite(u, "", "", time.Second, zerolog.Nop()); err == nil {
+				t.Fatalf("expected error for (%s)", u)
+			}
+		}
+		if _, err := NewGraphite("tcp://localhost:2003", "pickle", "", time.Second, zerolog.Nop()); err == nil {
+			t.Fatal("expected error for invalid format")
+		}
+	}
+
+	t.Log("\ttcp")
+	{
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer l.Close()
+		lines := make(chan string, 10)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			sc := bufio.NewScanner(conn)
+			for sc.Scan() {
+				lines <- sc.Text()
+			}
+		}()
+
+		s, err := NewGraphite("tcp://"+l.Addr().String(), GraphiteFormatTagged, "cca", time.Second, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		c := &circonus.Check{}
+		mn := c.MetricNameWithStreamTags("CPUUtilization`Average", circonus.Tags{{Category: "instance-id", Value: "i-123"}})
+		ts := time.Unix(1700000060, 0)
+		b := s.NewBatch()
+		if err := b.WriteMetricSample(mn, "n", 2.5, &ts); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := b.WriteMetricSample("state", "s", "running", nil); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := b.Submit(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		select {
+		case line := <-lines:
+			expect := "cca.CPUUtilization.Average;instance-id=i-123 2.5 1700000060"
+			if line != expect {
+				t.Fatalf("expected (%s), got (%s)", expect, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for line")
+		}
+	}
+
+	t.Log("\tudp datagrams")
+	{
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer pc.Close()
+
+		s, err := NewGraphite("udp://"+pc.LocalAddr().String(), "", "", time.Second, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		samples := make([]Sample, 100)
+		for i := range samples {
+			samples[i] = Sample{Name: "NetworkIn`Sum", Type: "n", Value: float64(i), Tags: map[string]string{"instance-id": "i-0123456789abcdef"}, Timestamp: 1700000060000}
+		}
+		if err := s.(*graphiteSink).send(samples); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 65535)
+		received := 0
+		for received < len(samples) {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+			if n > maxGraphiteDatagram {
+				t.Fatalf("expected datagram <= %d bytes, got %d", maxGraphiteDatagram, n)
+			}
+			if !strings.HasSuffix(string(buf[:n]), "\n") {
+				t.Fatal("expected datagram to end on a line boundary")
+			}
+			received += strings.Count(string(buf[:n]), "\n")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// httpSink POSTs each batch of samples, encoded by encode, to a url.
+type httpSink struct {
+	client  *http.Client
+	encode  func([]Sample) ([]byte, error)
+	headers map[string]string
+	url     string
+	logger  zerolog.Logger
+}
+
+// NewHTTP returns a sink which POSTs each batch of samples, as a JSON array,
+// to destURL with any additional headers (e.g. Authorization).
+func NewHTTP(destURL string, headers map[string]string, timeout time.Duration, logger zerolog.Logger) (circonus.Sink, error) {
+	return newHTTPSink(destURL, headers, map[string]string{"Content-Type": "application/json"}, encodeJSON, timeout, logger)
+}
+
+// newHTTPSink returns an http sink, protoHeaders are required by the protocol
+// encode implements and take precedence over the configured headers.
+func newHTTPSink(destURL string, headers, protoHeaders map[string]string, encode func([]Sample) ([]byte, error), timeout time.Duration, logger zerolog.Logger) (*httpSink, error) {
+	if destURL == "" {
+		return nil, errors.New("invalid url (empty)")
+	}
+	if _, err := url.Parse(destURL); err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+
+	hdrs := make(map[string]string, len(headers)+len(protoHeaders))
+	for k, v := range headers {
+		hdrs[k] = v
+	}
+	for k, v := range protoHeaders {
+		hdrs[k] = v
+	}
+
+	return &httpSink{
+		client:  &http.Client{Timeout: timeout},
+		encode:  encode,
+		headers: hdrs,
+		url:     destURL,
+		logger:  logger.With().Str("pkg", "sinks").Str("url", destURL).Logger(),
+	}, nil
+}
+
+func (h *httpSink) NewBatch() circonus.Batch {
+	return &jsonBatch{deliver: h.post}
+}
+
+// Close closes the sink's idle connections.
+func (h *httpSink) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+func (h *httpSink) post(samples []Sample) error {
+	data, err := h.encode(samples)
+	if err != nil {
+		return errors.Wrap(err, "encoding samples")
+	}
+	if len(data) == 0 {
+		return nil // nothing the destination can represent
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("User-Agent", release.NAME+"/"+release.VERSION)
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "posting samples")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("posting samples, response %s (%s)", resp.Status, bytes.TrimSpace(body))
+	}
+
+	h.logger.Debug().Int("samples", len(samples)).Msg("samples posted")
+	return nil
+}
+
+func encodeJSON(samples []Sample) ([]byte, error) {
+	return json.Marshal(samples)
+}
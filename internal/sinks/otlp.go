@@ -0,0 +1,266 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// OTLPProtocolGRPC exports metrics with the OTLP gRPC MetricsService (e.g. localhost:4317).
+	OTLPProtocolGRPC = "grpc"
+	// OTLPProtocolHTTP exports metrics with OTLP/HTTP binary protobuf (e.g. http://localhost:4318/v1/metrics).
+	OTLPProtocolHTTP = "http"
+)
+
+// defaultResourceTags are the tag categories used as resource attributes,
+// they identify where metrics came from rather than describing the metric.
+// Tag categories prefixed with resource_ (e.g. Azure resource_name) are
+// also resource attributes.
+var defaultResourceTags = []string{"aws_region", "project_id"}
+
+// otlpGRPC exports batches with the OTLP gRPC MetricsService.
+type otlpGRPC struct {
+	conn    *grpc.ClientConn
+	client  colmetricpb.MetricsServiceClient
+	md      metadata.MD
+	timeout time.Duration
+	resTags map[string]bool
+	logger  zerolog.Logger
+}
+
+// NewOTLP returns a sink which exports each batch of samples to an
+// OpenTelemetry receiver (collector, or a backend accepting OTLP). Samples
+// become gauges, or monotonic delta sums for metrics configured as counters.
+// Tags in the resource tag categories (see defaultResourceTags and
+// Config.ResourceTags) become resource attributes, the remaining tags become
+// data point attributes. Text samples are skipped.
+func NewOTLP(cfg Config, timeout time.Duration, logger zerolog.Logger) (circonus.Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("invalid url (empty)")
+	}
+
+	resTags := make(map[string]bool)
+	for _, t := range defaultResourceTags {
+		resTags[t] = true
+	}
+	for _, t := range cfg.ResourceTags {
+		resTags[strings.ToLower(t)] = true
+	}
+
+	switch strings.ToLower(cfg.Protocol) {
+	case "", OTLPProtocolGRPC:
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if cfg.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.Dial(cfg.URL, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating otlp grpc client")
+		}
+		md := metadata.New(nil)
+		for k, v := range cfg.Headers {
+			md.Set(strings.ToLower(k), v)
+		}
+		return &otlpGRPC{
+			conn:    conn,
+			client:  colmetricpb.NewMetricsServiceClient(conn),
+			md:      md,
+			timeout: timeout,
+			resTags: resTags,
+			logger:  logger.With().Str("pkg", "sinks").Str("otlp_endpoint", cfg.URL).Logger(),
+		}, nil
+	case OTLPProtocolHTTP:
+		encode := func(samples []Sample) ([]byte, error) {
+			req := otlpRequest(samples, resTags)
+			if req == nil {
+				return nil, nil
+			}
+			return proto.Marshal(req)
+		}
+		return newHTTPSink(cfg.URL, cfg.Headers, map[string]string{"Content-Type": "application/x-protobuf"}, encode, timeout, logger)
+	default:
+		return nil, errors.Errorf("unknown otlp protocol (%s)", cfg.Protocol)
+	}
+}
+
+func (o *otlpGRPC) NewBatch() circonus.Batch {
+	return &jsonBatch{deliver: o.export}
+}
+
+// Close closes the grpc connection.
+func (o *otlpGRPC) Close() error {
+	return o.conn.Close()
+}
+
+func (o *otlpGRPC) export(samples []Sample) error {
+	req := otlpRequest(samples, o.resTags)
+	if req == nil {
+		return nil
+	}
+
+	ctx := metadata.NewOutgoingContext(context.Background(), o.md)
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	resp, err := o.client.Export(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "exporting metrics")
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		o.logger.Warn().Int64("rejected", ps.GetRejectedDataPoints()).Str("reason", ps.GetErrorMessage()).Msg("otlp receiver rejected data points")
+	}
+
+	o.logger.Debug().Int("samples", len(samples)).Msg("samples exported")
+	return nil
+}
+
+// otlpRequest converts samples to an export request, samples are grouped by
+// resource (the resource attributes) and metric. Returns nil if there are no
+// samples which can be represented.
+func otlpRequest(samples []Sample, resTags map[string]bool) *colmetricpb.ExportMetricsServiceRequest {
+	type resource struct {
+		rm      *metricpb.ResourceMetrics
+		metrics map[string]*metricpb.Metric
+	}
+	resources := make(map[string]*resource)
+	req := &colmetricpb.ExportMetricsServiceRequest{}
+
+	for _, s := range samples {
+		if s.Type == circonus.MetricTypeString {
+			continue
+		}
+		dp := &metricpb.NumberDataPoint{TimeUnixNano: uint64(s.Timestamp) * uint64(time.Millisecond)}
+		if !otlpValue(dp, s.Value) {
+			continue
+		}
+
+		var resAttrs, attrs []*commonpb.KeyValue
+		for _, cat := range sortedTagCategories(s.Tags) {
+			kv := &commonpb.KeyValue{Key: cat, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s.Tags[cat]}}}
+			if resTags[cat] || strings.HasPrefix(cat, "resource_") {
+				resAttrs = append(resAttrs, kv)
+			} else {
+				attrs = append(attrs, kv)
+			}
+		}
+		dp.Attributes = attrs
+
+		rkey := attrsKey(resAttrs)
+		r, ok := resources[rkey]
+		if !ok {
+			resAttrs = append(resAttrs, &commonpb.KeyValue{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: release.NAME}}})
+			r = &resource{
+				rm: &metricpb.ResourceMetrics{
+					Resource: &resourcepb.Resource{Attributes: resAttrs},
+					ScopeMetrics: []*metricpb.ScopeMetrics{{
+						Scope: &commonpb.InstrumentationScope{Name: release.NAME, Version: release.VERSION},
+					}},
+				},
+				metrics: make(map[string]*metricpb.Metric),
+			}
+			resources[rkey] = r
+			req.ResourceMetrics = append(req.ResourceMetrics, r.rm)
+		}
+
+		mkey := s.Name
+		if s.Counter {
+			mkey += "\xffsum"
+		}
+		m, ok := r.metrics[mkey]
+		if !ok {
+			m = &metricpb.Metric{Name: s.Name}
+			if s.Counter {
+				m.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+					AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+					IsMonotonic:            true,
+				}}
+			} else {
+				m.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{}}
+			}
+			r.metrics[mkey] = m
+			sm := r.rm.ScopeMetrics[0]
+			sm.Metrics = append(sm.Metrics, m)
+		}
+		switch d := m.Data.(type) {
+		case *metricpb.Metric_Sum:
+			d.Sum.DataPoints = append(d.Sum.DataPoints, dp)
+		case *metricpb.Metric_Gauge:
+			d.Gauge.DataPoints = append(d.Gauge.DataPoints, dp)
+		}
+	}
+
+	if len(req.ResourceMetrics) == 0 {
+		return nil
+	}
+	return req
+}
+
+// otlpValue sets the data point value, false if the value is not numeric.
+func otlpValue(dp *metricpb.NumberDataPoint, v interface{}) bool {
+	switch val := v.(type) {
+	case float64:
+		dp.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: val}
+	case float32:
+		dp.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: float64(val)}
+	case int:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: int64(val)}
+	case int32:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: int64(val)}
+	case int64:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: val}
+	case uint:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: int64(val)}
+	case uint32:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: int64(val)}
+	case uint64:
+		dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: int64(val)}
+	default:
+		return false
+	}
+	return true
+}
+
+func sortedTagCategories(tags map[string]string) []string {
+	cats := make([]string, 0, len(tags))
+	for cat := range tags {
+		cats = append(cats, cat)
+	}
+	sort.Strings(cats)
+	return cats
+}
+
+func attrsKey(attrs []*commonpb.KeyValue) string {
+	var sb strings.Builder
+	for _, kv := range attrs {
+		sb.WriteString(kv.GetKey())
+		sb.WriteByte(0xff)
+		sb.WriteString(kv.GetValue().GetStringValue())
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
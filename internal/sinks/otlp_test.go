@@ -0,0 +1,191 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// writeOTLPBatch writes a gauge, a counter and a text sample to a batch from s.
+func writeOTLPBatch(t *testing.T, s circonus.Sink) {
+	t.Helper()
+
+	c := &circonus.Check{}
+	ts := time.Unix(1700000060, 0)
+	gauge := c.MetricNameWithStreamTags("CPUUtilization`Average", circonus.Tags{{Category: "aws_region", Value: "us-east-1"}, {Category: "instance-id", Value: "i-123"}})
+	counter := c.MetricNameWithStreamTags("NetworkIn`Sum", circonus.Tags{{Category: "aws_region", Value: "us-east-1"}, {Category: "instance-id", Value: "i-123"}})
+
+	b := s.NewBatch()
+	if err := b.WriteMetricSample(gauge, "n", 2.5, &ts); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := circonus.WriteCounterSample(b, counter, 1024, &ts); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.WriteMetricSample("state", "s", "running", nil); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.Submit(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+}
+
+// checkOTLPRequest verifies the request produced by writeOTLPBatch.
+func checkOTLPRequest(t *testing.T, req *colmetricpb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	if req == nil || len(req.GetResourceMetrics()) != 1 {
+		t.Fatalf("expected 1 resource, got %v", req)
+	}
+	rm := req.GetResourceMetrics()[0]
+	resAttrs := map[string]string{}
+	for _, kv := range rm.GetResource().GetAttributes() {
+		resAttrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if resAttrs["aws_region"] != "us-east-1" || resAttrs["service.name"] == "" {
+		t.Fatalf("unexpected resource attributes %v", resAttrs)
+	}
+
+	metrics := rm.GetScopeMetrics()[0].GetMetrics()
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics (text sample skipped), got %d", len(metrics))
+	}
+
+	gauge := metrics[0].GetGauge()
+	if gauge == nil || len(gauge.GetDataPoints()) != 1 {
+		t.Fatalf("expected %s to be a gauge, got %v", metrics[0].GetName(), metrics[0])
+	}
+	dp := gauge.GetDataPoints()[0]
+	if dp.GetAsDouble() != 2.5 || dp.GetTimeUnixNano() != uint64(time.Unix(1700000060, 0).UnixNano()) {
+		t.Fatalf("unexpected data point %v", dp)
+	}
+	if len(dp.GetAttributes()) != 1 || dp.GetAttributes()[0].GetKey() != "instance-id" {
+		t.Fatalf("expected instance-id data point attribute, got %v", dp.GetAttributes())
+	}
+
+	sum := metrics[1].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || len(sum.GetDataPoints()) != 1 || sum.GetDataPoints()[0].GetAsDouble() != 1024 {
+		t.Fatalf("expected %s to be a monotonic sum, got %v", metrics[1].GetName(), metrics[1])
+	}
+}
+
+func TestOTLPRequest(t *testing.T) {
+	t.Log("Testing otlpRequest")
+
+	t.Log("\tno representable samples")
+	{
+		if req := otlpRequest([]Sample{{Name: "state", Type: "s", Value: "running"}}, nil); req != nil {
+			t.Fatalf("expected nil request, got %v", req)
+		}
+	}
+
+	t.Log("\tresource grouping")
+	{
+		samples := []Sample{
+			{Name: "a", Type: "n", Value: 1.0, Tags: map[string]string{"resource_name": "vm1", "units": "percent"}},
+			{Name: "a", Type: "n", Value: 2.0, Tags: map[string]string{"resource_name": "vm2", "units": "percent"}},
+			{Name: "b", Type: "L", Value: int64(3), Tags: map[string]string{"resource_name": "vm1", "zone": "z1"}},
+		}
+		req := otlpRequest(samples, map[string]bool{"zone": true})
+		if len(req.GetResourceMetrics()) != 3 {
+			t.Fatalf("expected 3 resources, got %d", len(req.GetResourceMetrics()))
+		}
+		m := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+		if len(m) != 1 || m[0].GetGauge().GetDataPoints()[0].GetAttributes()[0].GetKey() != "units" {
+			t.Fatalf("unexpected metrics %v", m)
+		}
+		m = req.GetResourceMetrics()[2].GetScopeMetrics()[0].GetMetrics()
+		if dp := m[0].GetGauge().GetDataPoints()[0]; dp.GetAsInt() != 3 || len(dp.GetAttributes()) != 0 {
+			t.Fatalf("unexpected data point %v", dp)
+		}
+	}
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	t.Log("Testing otlp sink (http)")
+
+	var received *colmetricpb.ExportMetricsServiceRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &colmetricpb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = req
+	}))
+	defer ts.Close()
+
+	s, err := NewOTLP(Config{URL: ts.URL, Protocol: "http", Headers: map[string]string{"Authorization": "Bearer abc"}}, time.Second, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	writeOTLPBatch(t, s)
+	checkOTLPRequest(t, received)
+}
+
+type testMetricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	md  metadata.MD
+	req *colmetricpb.ExportMetricsServiceRequest
+}
+
+func (s *testMetricsService) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	s.req = req
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	t.Log("Testing otlp sink (grpc)")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	svc := &testMetricsService{}
+	srv := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(srv, svc)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	t.Log("\tinvalid protocol")
+	{
+		if _, err := NewOTLP(Config{URL: l.Addr().String(), Protocol: "udp"}, time.Second, zerolog.Nop()); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		s, err := NewOTLP(Config{URL: l.Addr().String(), Insecure: true, Headers: map[string]string{"X-Token": "abc"}}, 5*time.Second, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		writeOTLPBatch(t, s)
+		checkOTLPRequest(t, svc.req)
+		if v := svc.md.Get("x-token"); len(v) != 1 || v[0] != "abc" {
+			t.Fatalf("expected x-token metadata, got %v", svc.md)
+		}
+	}
+}
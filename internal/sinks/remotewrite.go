@@ -0,0 +1,180 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/golang/snappy"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// NewRemoteWrite returns a sink which sends each batch of samples to a
+// Prometheus remote_write endpoint (e.g. Mimir, Thanos receive, Prometheus
+// with --web.enable-remote-write-receiver) as a snappy compressed protobuf
+// WriteRequest. See promName and promLabelName for how metric names and
+// tags are mapped. Text samples, which Prometheus cannot represent, are
+// skipped.
+func NewRemoteWrite(destURL string, headers map[string]string, timeout time.Duration, logger zerolog.Logger) (circonus.Sink, error) {
+	return newHTTPSink(destURL, headers, map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}, encodeRemoteWrite, timeout, logger)
+}
+
+// promSeries is a time series in a remote_write request.
+type promSeries struct {
+	labels  [][2]string // sorted by name, including __name__
+	samples []promSample
+}
+
+type promSample struct {
+	value     float64
+	timestamp int64 // milliseconds
+}
+
+// encodeRemoteWrite encodes samples as a snappy compressed prometheus.WriteRequest.
+// Samples of the same series are grouped and ordered by timestamp, as remote_write requires.
+func encodeRemoteWrite(samples []Sample) ([]byte, error) {
+	series := make(map[string]*promSeries)
+	keys := []string{}
+	for _, s := range samples {
+		if s.Type == circonus.MetricTypeString {
+			continue
+		}
+		v, ok := floatValue(s.Value)
+		if !ok {
+			continue
+		}
+		labels := promLabels(s)
+		key := seriesKey(labels)
+		ps, found := series[key]
+		if !found {
+			ps = &promSeries{labels: labels}
+			series[key] = ps
+			keys = append(keys, key)
+		}
+		ps.samples = append(ps.samples, promSample{value: v, timestamp: s.Timestamp})
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	// WriteRequest { repeated TimeSeries timeseries = 1; }
+	var req []byte
+	for _, key := range keys {
+		ps := series[key]
+		sort.SliceStable(ps.samples, func(i, j int) bool { return ps.samples[i].timestamp < ps.samples[j].timestamp })
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, encodeSeries(ps))
+	}
+
+	return snappy.Encode(nil, req), nil
+}
+
+// encodeSeries encodes a prometheus.TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }.
+func encodeSeries(ps *promSeries) []byte {
+	var ts []byte
+	for _, l := range ps.labels {
+		// Label { string name = 1; string value = 2; }
+		var lb []byte
+		lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+		lb = protowire.AppendString(lb, l[0])
+		lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+		lb = protowire.AppendString(lb, l[1])
+		ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, lb)
+	}
+	for _, s := range ps.samples {
+		// Sample { double value = 1; int64 timestamp = 2; }
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(s.value))
+		sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sb)
+	}
+	return ts
+}
+
+// promLabels returns the sorted labels for a sample, __name__ and one label per tag.
+func promLabels(s Sample) [][2]string {
+	lm := make(map[string]string, len(s.Tags)+1)
+	for cat, val := range s.Tags {
+		if name := promLabelName(cat); name != "" && val != "" {
+			lm[name] = val
+		}
+	}
+	lm["__name__"] = promName(s.Name)
+
+	labels := make([][2]string, 0, len(lm))
+	for k, v := range lm {
+		labels = append(labels, [2]string{k, v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	return labels
+}
+
+func seriesKey(labels [][2]string) string {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(l[0])
+		sb.WriteByte(0xff)
+		sb.WriteString(l[1])
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
+
+// promName maps a metric name to a valid Prometheus metric name
+// ([a-zA-Z_:][a-zA-Z0-9_:]*). The metric name separator (e.g. the backtick
+// in CPUUtilization`Average) and any other invalid characters are replaced
+// with underscores, e.g. CPUUtilization_Average.
+func promName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// promLabelName maps a tag category to a valid Prometheus label name
+// ([a-zA-Z_][a-zA-Z0-9_]*). Names beginning with __ are reserved, they are
+// prefixed with tag.
+func promLabelName(category string) string {
+	name := sanitizeName(category, false)
+	if strings.HasPrefix(name, "__") {
+		name = "tag" + name
+	}
+	return name
+}
+
+func sanitizeName(s string, allowColon bool) string {
+	if s == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 1)
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r == ':' && allowColon:
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
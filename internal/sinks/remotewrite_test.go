@@ -0,0 +1,165 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/golang/snappy"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPromName(t *testing.T) {
+	t.Log("Testing promName")
+
+	tests := map[string]string{
+		"CPUUtilization`Average": "CPUUtilization_Average",
+		"Percentage CPU`average": "Percentage_CPU_average",
+		"compute.googleapis.com": "compute_googleapis_com",
+		"5xxErrorRate":           "_5xxErrorRate",
+		"ns:metric":              "ns:metric",
+	}
+	for in, expect := range tests {
+		if got := promName(in); got != expect {
+			t.Fatalf("expected %s for %s, got %s", expect, in, got)
+		}
+	}
+
+	if got := promLabelName("__name__"); got != "tag__name__" {
+		t.Fatalf("expected reserved label to be prefixed, got %s", got)
+	}
+	if got := promLabelName("aws:region"); got != "aws_region" {
+		t.Fatalf("expected aws_region, got %s", got)
+	}
+}
+
+// rwSeries is a decoded remote_write time series.
+type rwSeries struct {
+	labels map[string]string
+	values []float64
+	stamps []int64
+}
+
+// decodeWriteRequest decodes the subset of prometheus.WriteRequest the sink encodes.
+func decodeWriteRequest(t *testing.T, data []byte) []rwSeries {
+	t.Helper()
+
+	fields := func(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("invalid tag")
+			}
+			b = b[n:]
+			m := fn(num, typ, b)
+			if m < 0 {
+				t.Fatalf("invalid field %d", num)
+			}
+			b = b[m:]
+		}
+	}
+
+	var series []rwSeries
+	fields(data, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		tsb, n := protowire.ConsumeBytes(b)
+		s := rwSeries{labels: map[string]string{}}
+		fields(tsb, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			mb, n := protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				var name, value string
+				fields(mb, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					v, n := protowire.ConsumeString(b)
+					if num == 1 {
+						name = v
+					} else {
+						value = v
+					}
+					return n
+				})
+				s.labels[name] = value
+			case 2:
+				fields(mb, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					if num == 1 {
+						v, n := protowire.ConsumeFixed64(b)
+						s.values = append(s.values, math.Float64frombits(v))
+						return n
+					}
+					v, n := protowire.ConsumeVarint(b)
+					s.stamps = append(s.stamps, int64(v))
+					return n
+				})
+			}
+			return n
+		})
+		series = append(series, s)
+		return n
+	})
+	return series
+}
+
+func TestRemoteWrite(t *testing.T) {
+	t.Log("Testing remote_write sink")
+
+	var received []rwSeries
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = decodeWriteRequest(t, data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	s, err := NewRemoteWrite(ts.URL, nil, time.Second, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	c := &circonus.Check{}
+	mn := c.MetricNameWithStreamTags("CPUUtilization`Average", circonus.Tags{{Category: "instance-id", Value: "i-123"}})
+	t2 := time.Unix(1700000120, 0)
+	t1 := time.Unix(1700000060, 0)
+
+	b := s.NewBatch()
+	if err := b.WriteMetricSample(mn, "n", 2.5, &t2); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.WriteMetricSample(mn, "n", 1.5, &t1); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.WriteMetricSample("state", "s", "running", nil); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.Submit(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 series (text sample skipped), got %d", len(received))
+	}
+	rs := received[0]
+	if rs.labels["__name__"] != "CPUUtilization_Average" || rs.labels["instance_id"] != "i-123" {
+		t.Fatalf("unexpected labels %v", rs.labels)
+	}
+	if len(rs.values) != 2 || rs.values[0] != 1.5 || rs.stamps[0] != t1.UnixMilli() || rs.stamps[1] != t2.UnixMilli() {
+		t.Fatalf("expected samples in timestamp order, got %v %v", rs.values, rs.stamps)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package sinks provides additional destinations for collected metric
+// samples, so the same data submitted to Circonus can also be routed to
+// other systems (see circonus.Sink).
+package sinks
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-cloud-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Config defines an additional sink for an instance's metric samples.
+type Config struct {
+	Headers      map[string]string `json:"headers" toml:"headers" yaml:"headers"`                   // http, prometheus_remote_write, otlp: additional request headers (e.g. Authorization)
+	Type         string            `json:"type" toml:"type" yaml:"type"`                            // REQUIRED, file|stdout|http|prometheus_remote_write|otlp|graphite
+	Path         string            `json:"path" toml:"path" yaml:"path"`                            // file: samples are appended to this file
+	URL          string            `json:"url" toml:"url" yaml:"url"`                               // http, prometheus_remote_write, otlp, graphite: each batch of samples is sent to this url (otlp grpc: host:port, graphite: tcp://host:port or udp://host:port)
+	Timeout      string            `json:"timeout" toml:"timeout" yaml:"timeout"`                   // http, prometheus_remote_write, otlp, graphite: request (graphite: connect/write) timeout, DEFAULT 30s
+	Protocol     string            `json:"protocol" toml:"protocol" yaml:"protocol"`                // otlp: grpc|http, DEFAULT grpc
+	ResourceTags []string          `json:"resource_tags" toml:"resource_tags" yaml:"resource_tags"` // otlp: additional tag categories to use as resource attributes
+	Format       string            `json:"format" toml:"format" yaml:"format"`                      // graphite: path|tagged, DEFAULT path
+	Prefix       string            `json:"prefix" toml:"prefix" yaml:"prefix"`                      // graphite: prepended to every metric path
+	Insecure     bool              `json:"insecure" toml:"insecure" yaml:"insecure"`                // otlp grpc: connect without tls
+}
+
+const (
+	// TypeFile appends samples, one JSON document per line, to a file.
+	TypeFile = "file"
+	// TypeStdout writes samples, one JSON document per line, to stdout.
+	TypeStdout = "stdout"
+	// TypeHTTP POSTs each batch of samples, as a JSON array, to a url.
+	TypeHTTP = "http"
+	// TypeRemoteWrite sends each batch of samples to a Prometheus remote_write endpoint.
+	TypeRemoteWrite = "prometheus_remote_write"
+	// TypeOTLP exports each batch of samples to an OpenTelemetry (OTLP) receiver.
+	TypeOTLP = "otlp"
+	// TypeGraphite sends each batch of samples to a Graphite plaintext protocol listener.
+	TypeGraphite = "graphite"
+)
+
+// Validate returns the problems found with the sink configuration, without
+// creating the sink (e.g. opening files or connections).
+func (cfg Config) Validate() []error {
+	var errs []error
+	required := func(field, v string) {
+		if v == "" {
+			errs = append(errs, config.FieldErrorf(field, "required"))
+		}
+	}
+
+	typ := strings.ToLower(cfg.Type)
+	switch typ {
+	case TypeFile:
+		required("path", cfg.Path)
+	case TypeStdout:
+	case TypeHTTP, TypeRemoteWrite, TypeOTLP, TypeGraphite:
+		required("url", cfg.URL)
+		if cfg.Timeout != "" {
+			if _, err := time.ParseDuration(cfg.Timeout); err != nil {
+				errs = append(errs, config.FieldErrorf("timeout", "invalid duration (%s)", cfg.Timeout))
+			}
+		}
+	case "":
+		errs = append(errs, config.FieldErrorf("type", "required"))
+	default:
+		errs = append(errs, config.FieldErrorf("type", "unknown sink type (%s), expected file, stdout, http, prometheus_remote_write, otlp, or graphite", cfg.Type))
+	}
+
+	switch typ {
+	case TypeOTLP:
+		switch strings.ToLower(cfg.Protocol) {
+		case "", OTLPProtocolGRPC, OTLPProtocolHTTP:
+		default:
+			errs = append(errs, config.FieldErrorf("protocol", "unknown otlp protocol (%s), expected grpc or http", cfg.Protocol))
+		}
+	case TypeGraphite:
+		if cfg.URL != "" {
+			if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") || u.Port() == "" {
+				errs = append(errs, config.FieldErrorf("url", "invalid graphite url (%s), expected tcp://host:port or udp://host:port", cfg.URL))
+			}
+		}
+		switch strings.ToLower(cfg.Format) {
+		case "", GraphiteFormatPath, GraphiteFormatTagged:
+		default:
+			errs = append(errs, config.FieldErrorf("format", "unknown graphite format (%s), expected path or tagged", cfg.Format))
+		}
+	}
+
+	return errs
+}
+
+// Sample is the JSON representation of a metric sample used by sinks.
+type Sample struct {
+	Value     interface{}       `json:"value"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Counter   bool              `json:"counter,omitempty"` // configured as a counter (e.g. CirconusMetric.Type counter)
+	Timestamp int64             `json:"timestamp"`         // milliseconds
+}
+
+// New returns primary, or if additional sinks are configured, a sink which
+// delivers every batch to primary and each of the additional sinks.
+func New(primary circonus.Sink, cfgs []Config, logger zerolog.Logger) (circonus.Sink, error) {
+	if primary == nil {
+		return nil, errors.New("invalid primary sink (nil)")
+	}
+	if len(cfgs) == 0 {
+		return primary, nil
+	}
+
+	others := make([]circonus.Sink, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		s, err := newSink(cfg, logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "sink %d (%s)", i+1, cfg.Type)
+		}
+		others = append(others, s)
+	}
+
+	return Tee(primary, logger, others...), nil
+}
+
+func newSink(cfg Config, logger zerolog.Logger) (circonus.Sink, error) {
+	switch strings.ToLower(cfg.Type) {
+	case TypeFile:
+		return NewFile(cfg.Path)
+	case TypeStdout:
+		return NewStdout(), nil
+	case TypeHTTP, TypeRemoteWrite, TypeOTLP, TypeGraphite:
+		timeout := 30 * time.Second
+		if cfg.Timeout != "" {
+			t, err := time.ParseDuration(cfg.Timeout)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing timeout")
+			}
+			timeout = t
+		}
+		switch strings.ToLower(cfg.Type) {
+		case TypeRemoteWrite:
+			return NewRemoteWrite(cfg.URL, cfg.Headers, timeout, logger)
+		case TypeOTLP:
+			return NewOTLP(cfg, timeout, logger)
+		case TypeGraphite:
+			return NewGraphite(cfg.URL, cfg.Format, cfg.Prefix, timeout, logger)
+		default:
+			return NewHTTP(cfg.URL, cfg.Headers, timeout, logger)
+		}
+	default:
+		return nil, errors.Errorf("unknown sink type (%s)", cfg.Type)
+	}
+}
+
+// Close releases the resources held by sink (e.g. connections), if any, once
+// it is no longer used. Sinks sharing resources (file, stdout, graphite) are
+// not closed.
+func Close(sink circonus.Sink) error {
+	if c, ok := sink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// jsonBatch holds samples in memory until submitted to deliver.
+type jsonBatch struct {
+	deliver func([]Sample) error
+	samples []Sample
+}
+
+func (b *jsonBatch) WriteCounterSample(metricName string, value float64, timestamp *time.Time) error {
+	if err := b.WriteMetricSample(metricName, circonus.MetricTypeFloat64, value, timestamp); err != nil {
+		return err
+	}
+	b.samples[len(b.samples)-1].Counter = true
+	return nil
+}
+
+func (b *jsonBatch) WriteMetricSample(metricName, metricType string, value interface{}, timestamp *time.Time) error {
+	if metricName == "" {
+		return errors.New("invalid metric name (empty)")
+	}
+	if metricType == "" {
+		return errors.New("invalid metric type (empty)")
+	}
+
+	name, tags := circonus.ParseMetricName(metricName)
+	s := Sample{
+		Name:  name,
+		Type:  metricType,
+		Value: value,
+	}
+	if len(tags) > 0 {
+		s.Tags = make(map[string]string, len(tags))
+		for _, t := range tags {
+			s.Tags[t.Category] = t.Value
+		}
+	}
+	if timestamp != nil {
+		s.Timestamp = timestamp.UTC().UnixMilli()
+	} else {
+		s.Timestamp = time.Now().UTC().UnixMilli()
+	}
+
+	b.samples = append(b.samples, s)
+	return nil
+}
+
+func (b *jsonBatch) Len() int {
+	return len(b.samples)
+}
+
+func (b *jsonBatch) Submit() error {
+	defer b.Discard()
+	if len(b.samples) == 0 {
+		return nil
+	}
+	return b.deliver(b.samples)
+}
+
+func (b *jsonBatch) Discard() {
+	b.samples = nil
+}
+
+// floatValue returns the sample value as a float64, false if it is not numeric.
+func floatValue(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
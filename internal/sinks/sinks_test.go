@@ -0,0 +1,161 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+// memSink records the samples submitted to it, or fails with err if set.
+type memSink struct {
+	err     error
+	batches [][]Sample
+}
+
+func (m *memSink) NewBatch() circonus.Batch {
+	return &jsonBatch{deliver: func(s []Sample) error {
+		if m.err != nil {
+			return m.err
+		}
+		m.batches = append(m.batches, s)
+		return nil
+	}}
+}
+
+func TestNew(t *testing.T) {
+	t.Log("Testing New")
+
+	primary := &memSink{}
+
+	t.Log("no additional sinks")
+	{
+		s, err := New(primary, nil, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if s != primary {
+			t.Fatal("expected primary sink")
+		}
+	}
+
+	t.Log("invalid")
+	{
+		for _, cfg := range []Config{{Type: "foo"}, {Type: TypeFile}, {Type: TypeHTTP}, {Type: TypeHTTP, URL: "http://localhost", Timeout: "foo"}} {
+			if _, err := New(primary, []Config{cfg}, zerolog.Nop()); err == nil {
+				t.Fatalf("expected error for %+v", cfg)
+			}
+		}
+	}
+}
+
+func TestTee(t *testing.T) {
+	t.Log("Testing Tee")
+
+	var posted []Sample
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer foo" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "samples.json")
+	primary := &memSink{}
+	s, err := New(primary, []Config{
+		{Type: TypeFile, Path: path},
+		{Type: TypeHTTP, URL: ts.URL, Headers: map[string]string{"Authorization": "Bearer foo"}},
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	c := &circonus.Check{}
+	ts1 := time.Unix(1700000000, 0)
+	b := s.NewBatch()
+	defer b.Discard()
+	if err := b.WriteMetricSample(c.MetricNameWithStreamTags("foo`Sum", circonus.Tags{{Category: "service", Value: "sqs"}}), "n", 1.5, &ts1); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if err := b.WriteMetricSample("bar", "L", uint64(2), nil); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if b.Len() == 0 {
+		t.Fatal("expected non-empty batch")
+	}
+	if err := b.Submit(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if b.Len() != 0 {
+		t.Fatal("expected batch to be reset")
+	}
+
+	t.Log("primary")
+	{
+		if len(primary.batches) != 1 || len(primary.batches[0]) != 2 {
+			t.Fatalf("unexpected primary batches %v", primary.batches)
+		}
+	}
+
+	t.Log("http")
+	{
+		if len(posted) != 2 {
+			t.Fatalf("expected 2 samples, got %v", posted)
+		}
+		if posted[0].Name != "foo`Sum" || posted[0].Tags["service"] != "sqs" || posted[0].Timestamp != ts1.UnixMilli() {
+			t.Fatalf("unexpected sample %+v", posted[0])
+		}
+		if posted[1].Timestamp == 0 {
+			t.Fatal("expected receipt timestamp")
+		}
+	}
+
+	t.Log("file")
+	{
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer f.Close()
+		lines := 0
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var s Sample
+			if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+			lines++
+		}
+		if lines != 2 {
+			t.Fatalf("expected 2 lines, got %d", lines)
+		}
+	}
+
+	t.Log("close")
+	{
+		if err := Close(s); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := Close(primary); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+}
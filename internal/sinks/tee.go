@@ -0,0 +1,112 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sinks
+
+import (
+	"time"
+
+	"github.com/circonus-labs/circonus-cloud-agent/internal/circonus"
+	"github.com/rs/zerolog"
+)
+
+// tee delivers each batch to a primary sink and any number of other sinks.
+type tee struct {
+	primary circonus.Sink
+	others  []circonus.Sink
+	logger  zerolog.Logger
+}
+
+// Tee returns a sink which delivers every batch to primary and to others.
+// Errors from primary are returned, errors from the other sinks are only
+// logged so they do not affect delivery to primary.
+func Tee(primary circonus.Sink, logger zerolog.Logger, others ...circonus.Sink) circonus.Sink {
+	return &tee{
+		primary: primary,
+		others:  others,
+		logger:  logger.With().Str("pkg", "sinks").Logger(),
+	}
+}
+
+func (t *tee) NewBatch() circonus.Batch {
+	b := &teeBatch{
+		primary: t.primary.NewBatch(),
+		others:  make([]circonus.Batch, len(t.others)),
+		logger:  t.logger,
+	}
+	for i, s := range t.others {
+		b.others[i] = s.NewBatch()
+	}
+	return b
+}
+
+// Close closes primary and the other sinks, returning the first error.
+func (t *tee) Close() error {
+	err := Close(t.primary)
+	for _, s := range t.others {
+		if cerr := Close(s); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+type teeBatch struct {
+	primary circonus.Batch
+	others  []circonus.Batch
+	logger  zerolog.Logger
+}
+
+func (b *teeBatch) WriteMetricSample(metricName, metricType string, value interface{}, timestamp *time.Time) error {
+	for i, o := range b.others {
+		if err := o.WriteMetricSample(metricName, metricType, value, timestamp); err != nil {
+			b.logger.Warn().Err(err).Int("sink", i+1).Str("metric", metricName).Msg("writing sample")
+		}
+	}
+	return b.primary.WriteMetricSample(metricName, metricType, value, timestamp)
+}
+
+func (b *teeBatch) WriteCounterSample(metricName string, value float64, timestamp *time.Time) error {
+	for i, o := range b.others {
+		if err := circonus.WriteCounterSample(o, metricName, value, timestamp); err != nil {
+			b.logger.Warn().Err(err).Int("sink", i+1).Str("metric", metricName).Msg("writing sample")
+		}
+	}
+	return circonus.WriteCounterSample(b.primary, metricName, value, timestamp)
+}
+
+// Len returns the size of the primary batch, or if it is empty (e.g. all
+// samples were discarded by the primary sink) the first non-empty other batch.
+func (b *teeBatch) Len() int {
+	if n := b.primary.Len(); n > 0 {
+		return n
+	}
+	for _, o := range b.others {
+		if n := o.Len(); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+func (b *teeBatch) Submit() error {
+	for i, o := range b.others {
+		if err := o.Submit(); err != nil {
+			b.logger.Error().Err(err).Int("sink", i+1).Msg("submitting samples")
+		}
+	}
+	if b.primary.Len() == 0 {
+		b.primary.Discard()
+		return nil
+	}
+	return b.primary.Submit()
+}
+
+func (b *teeBatch) Discard() {
+	for _, o := range b.others {
+		o.Discard()
+	}
+	b.primary.Discard()
+}
@@ -0,0 +1,338 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package telemetry provides minimal counter and histogram metrics for
+// agent internals, exposed in the Prometheus text exposition format.
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets for histograms of durations in seconds.
+var DefaultBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Registry holds a set of metrics.
+type Registry struct {
+	metrics []metric
+	sync.Mutex
+}
+
+// Default registry used by the package level constructors and Handler.
+var Default = NewRegistry()
+
+type metric interface {
+	write(w io.Writer) error
+}
+
+// NewRegistry returns a new, empty, registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: []metric{}}
+}
+
+func (r *Registry) register(m metric) {
+	r.Lock()
+	defer r.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WritePrometheus writes all metrics in the registry to w in the
+// Prometheus text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if err := m.write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http handler serving the Default registry.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = Default.WritePrometheus(w)
+	})
+}
+
+// desc defines the name, help and label names shared by a metric family.
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (d *desc) header(w io.Writer, metricType string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, metricType)
+	return err
+}
+
+// labels encodes the label pairs for a series, extra is appended (e.g. le for histogram buckets).
+func (d *desc) labels(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		if v == "" {
+			continue // an empty label value is equivalent to the label not being present
+		}
+		pairs = append(pairs, d.labelNames[i]+`="`+escapeLabelValue(v)+`"`)
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+extra[1]+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// matches returns true if the leading label values of a series are prefix.
+func (d *desc) matches(values, prefix []string) bool {
+	if len(prefix) > len(values) {
+		return false
+	}
+	for i, v := range prefix {
+		if values[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labelNames) {
+		panic(fmt.Sprintf("%s: expected %d label values, got %d", d.name, len(d.labelNames), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	series map[string]*counter
+	desc
+	sync.Mutex
+}
+
+type counter struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec creates a counter family registered with the Default registry.
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labelNames...)
+}
+
+// NewCounterVec creates a counter family registered with the registry.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{
+		desc:   desc{name: name, help: help, labelNames: labelNames},
+		series: make(map[string]*counter),
+	}
+	r.register(v)
+	return v
+}
+
+// Inc increments the counter identified by the label values by one.
+func (v *CounterVec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Add adds delta (which must not be negative) to the counter identified by the label values.
+func (v *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	k := v.key(labelValues)
+
+	v.Lock()
+	defer v.Unlock()
+
+	c, ok := v.series[k]
+	if !ok {
+		c = &counter{labelValues: append([]string{}, labelValues...)}
+		v.series[k] = c
+	}
+	c.value += delta
+}
+
+// DeletePrefix removes the counters whose leading label values match
+// labelValues (e.g. all of the series for a stopped instance).
+func (v *CounterVec) DeletePrefix(labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+
+	for k, c := range v.series {
+		if v.matches(c.labelValues, labelValues) {
+			delete(v.series, k)
+		}
+	}
+}
+
+func (v *CounterVec) write(w io.Writer) error {
+	v.Lock()
+	defer v.Unlock()
+
+	if len(v.series) == 0 {
+		return nil
+	}
+	if err := v.header(w, "counter"); err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(v.series) {
+		c := v.series[k]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, v.labels(c.labelValues), formatFloat(c.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	series  map[string]*histogram
+	buckets []float64
+	desc
+	sync.Mutex
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64 // per bucket, not cumulative
+	sum         float64
+	count       uint64
+}
+
+// NewHistogramVec creates a histogram family registered with the Default registry.
+// If buckets is empty, DefaultBuckets are used.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labelNames...)
+}
+
+// NewHistogramVec creates a histogram family registered with the registry.
+// If buckets is empty, DefaultBuckets are used.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	v := &HistogramVec{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: b,
+		series:  make(map[string]*histogram),
+	}
+	r.register(v)
+	return v
+}
+
+// Observe adds a value to the histogram identified by the label values.
+func (v *HistogramVec) Observe(value float64, labelValues ...string) {
+	k := v.key(labelValues)
+
+	v.Lock()
+	defer v.Unlock()
+
+	h, ok := v.series[k]
+	if !ok {
+		h = &histogram{
+			labelValues: append([]string{}, labelValues...),
+			counts:      make([]uint64, len(v.buckets)),
+		}
+		v.series[k] = h
+	}
+	for i, ub := range v.buckets {
+		if value <= ub {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// DeletePrefix removes the histograms whose leading label values match
+// labelValues (e.g. all of the series for a stopped instance).
+func (v *HistogramVec) DeletePrefix(labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+
+	for k, h := range v.series {
+		if v.matches(h.labelValues, labelValues) {
+			delete(v.series, k)
+		}
+	}
+}
+
+func (v *HistogramVec) write(w io.Writer) error {
+	v.Lock()
+	defer v.Unlock()
+
+	if len(v.series) == 0 {
+		return nil
+	}
+	if err := v.header(w, "histogram"); err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(v.series) {
+		h := v.series[k]
+		var cumulative uint64
+		for i, ub := range v.buckets {
+			cumulative += h.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labels(h.labelValues, "le", formatFloat(ub)), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labels(h.labelValues, "le", "+Inf"), h.count); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n", v.name, v.labels(h.labelValues), formatFloat(h.sum)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_count%s %d\n", v.name, v.labels(h.labelValues), h.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
@@ -0,0 +1,83 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package telemetry
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	t.Log("Testing WritePrometheus")
+
+	r := NewRegistry()
+	calls := r.NewCounterVec("test_calls_total", "Test calls.", "service", "collector")
+	duration := r.NewHistogramVec("test_duration_seconds", "Test durations.", []float64{1, 5}, "service")
+
+	t.Log("empty")
+	{
+		var buf bytes.Buffer
+		if err := r.WritePrometheus(&buf); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("expected no output, got (%s)", buf.String())
+		}
+	}
+
+	calls.Inc("aws", "AWS/EC2")
+	calls.Add(2, "aws", "AWS/EC2")
+	calls.Inc("azure", "")
+	calls.Inc("gcp", `a"b`)
+	duration.Observe(0.5, "aws")
+	duration.Observe(3, "aws")
+	duration.Observe(10, "aws")
+
+	t.Log("valid")
+	{
+		var buf bytes.Buffer
+		if err := r.WritePrometheus(&buf); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		out := buf.String()
+		for _, expect := range []string{
+			"# TYPE test_calls_total counter\n",
+			`test_calls_total{service="aws",collector="AWS/EC2"} 3` + "\n",
+			`test_calls_total{service="azure"} 1` + "\n",
+			`test_calls_total{service="gcp",collector="a\"b"} 1` + "\n",
+			"# TYPE test_duration_seconds histogram\n",
+			`test_duration_seconds_bucket{service="aws",le="1"} 1` + "\n",
+			`test_duration_seconds_bucket{service="aws",le="5"} 2` + "\n",
+			`test_duration_seconds_bucket{service="aws",le="+Inf"} 3` + "\n",
+			`test_duration_seconds_sum{service="aws"} 13.5` + "\n",
+			`test_duration_seconds_count{service="aws"} 3` + "\n",
+		} {
+			if !strings.Contains(out, expect) {
+				t.Fatalf("expected %q in\n%s", expect, out)
+			}
+		}
+	}
+	t.Log("delete prefix")
+	{
+		calls.DeletePrefix("aws")
+		duration.DeletePrefix("aws")
+		var buf bytes.Buffer
+		if err := r.WritePrometheus(&buf); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		out := buf.String()
+		if strings.Contains(out, `service="aws"`) {
+			t.Fatalf("expected aws series removed\n%s", out)
+		}
+		if !strings.Contains(out, `test_calls_total{service="azure"} 1`) {
+			t.Fatalf("expected azure series kept\n%s", out)
+		}
+		if strings.Contains(out, "test_duration_seconds") {
+			t.Fatalf("expected empty histogram omitted\n%s", out)
+		}
+	}
+}